@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"amalgo/extract"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagExtractDir       string
+	flagExtractFormat    string
+	flagExtractDryRun    bool
+	flagExtractOverwrite bool
+)
+
+var extractCmd = &cobra.Command{
+	Use:   "extract <bundle>",
+	Short: "Rebuild a file tree from an amalgamated bundle.",
+	Long: `Extract parses a bundle produced by amalgo (markdown, json or xml) and
+writes each file back under the target directory. Paths that would escape
+the target directory are refused. Use '-' to read the bundle from stdin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExtract,
+}
+
+func init() {
+	rootCmd.AddCommand(extractCmd)
+
+	extractCmd.Flags().StringVarP(&flagExtractDir, "dir", "d", ".", "Target directory to write files into")
+	extractCmd.Flags().StringVarP(&flagExtractFormat, "format", "f", "", "Bundle format (default: detect from extension and content)")
+	extractCmd.Flags().BoolVarP(&flagExtractDryRun, "dry-run", "n", false, "List the files that would be written without writing them")
+	extractCmd.Flags().BoolVar(&flagExtractOverwrite, "overwrite", false, "Replace files that already exist in the target directory")
+}
+
+func runExtract(cmd *cobra.Command, args []string) error {
+	bundlePath := args[0]
+
+	var (
+		data []byte
+		err  error
+	)
+	if bundlePath == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(bundlePath)
+	}
+	if err != nil {
+		return fmt.Errorf("reading bundle: %w", err)
+	}
+
+	format := flagExtractFormat
+	if format == "" {
+		format = registry.DetectFormat(bundlePath, data)
+	}
+
+	extractor, err := registry.GetExtractor(format)
+	if err != nil {
+		return fmt.Errorf("%w\nAvailable formats: %s", err, strings.Join(registry.List(), ", "))
+	}
+
+	files, err := extractor.Extract(data)
+	if err != nil {
+		return fmt.Errorf("parsing bundle: %w", err)
+	}
+
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "No files found in bundle")
+		return nil
+	}
+
+	results, err := extract.Write(files, extract.Options{
+		TargetDir: flagExtractDir,
+		DryRun:    flagExtractDryRun,
+		Overwrite: flagExtractOverwrite,
+	})
+	if err != nil {
+		return fmt.Errorf("extracting files: %w", err)
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(os.Stderr, "%-9s %s (%d bytes)\n", r.Action, r.Path, r.Size)
+	}
+
+	if flagExtractDryRun {
+		fmt.Fprintf(os.Stderr, "Dry run: would write %d file(s) to %s\n", len(results), flagExtractDir)
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Extracted %d file(s) to %s\n", len(results), flagExtractDir)
+	return nil
+}
@@ -3,13 +3,25 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"math"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"amalgo/filter"
+	"amalgo/lang"
 	"amalgo/processor"
 	"amalgo/scanner"
+	"amalgo/tokenizer"
+	"amalgo/vcs"
 
 	"github.com/spf13/cobra"
 )
@@ -24,7 +36,37 @@ var (
 	flagFormat         string
 	flagGitignore      string
 	flagUseGitignore   bool
+	flagGitExcludes    bool
+	flagGitTracked     bool
+	flagRev            string
+	flagChangedSince   string
+	flagStaged         bool
+	flagUnstaged       bool
+	flagDiffContext    int
+	flagDiffFull       bool
+	flagJobs           int
 	flagIgnorePatterns []string
+	flagIncludes       []string
+	flagNames          []string
+	flagLangs          []string
+	flagShebang        bool
+	flagShowTokens     bool
+	flagTokenizer      string
+	flagTokenVocab     string
+	flagMaxTokens      int
+	flagPriority       string
+	flagBinary         string
+	flagMaxFileSize    string
+	flagMaxTotalSize   string
+	flagOversize       string
+	flagPriorityGlobs  []string
+	flagTruncate       bool
+	flagSplitSize      string
+	flagSplitTokens    int
+	flagTree           bool
+	flagTreeAnnotate   string
+	flagTOC            bool
+	flagMeta           bool
 )
 
 var (
@@ -35,7 +77,8 @@ var rootCmd = &cobra.Command{
 	Use:   "amalgo",
 	Short: "Concatenate files by extension into a single output file.",
 	Long: `Amalgo recursively scans a directory for files with given extension(s)
-and produces an amalgamated markdown file.
+and produces an amalgamated markdown, JSON or XML file, or a unified diff
+of the files changed in a git repository.
 Supports .gitignore patterns for flexible file filtering.
 Handy for passing a small project as context to LLMs or for documentation.`,
 	RunE: run,
@@ -51,21 +94,70 @@ func Execute() {
 func init() {
 	registry = processor.NewRegistry()
 	registry.Register(processor.NewMarkdownProcessor())
+	registry.Register(processor.NewJSONProcessor())
+	registry.Register(processor.NewXMLProcessor())
+	registry.Register(processor.NewDiffProcessor())
 
 	formats := strings.Join(registry.List(), ", ")
 
-	rootCmd.Flags().StringVarP(&flagDir, "dir", "d", ".", "Root directory to scan")
-	rootCmd.Flags().StringSliceVarP(&flagExts, "ext", "e", nil, "File extension(s) to include (e.g. .rs,.py or repeat -e) [required]")
+	addSelectionFlags(rootCmd)
 	rootCmd.Flags().StringVarP(&flagOut, "out", "o", "", "Output file path (use '-' for stdout, default: concat.<format>)")
-	rootCmd.Flags().StringSliceVarP(&flagIgnoreDirs, "ignore-dirs", "i", []string{".git", "node_modules", "vendor"}, "Directory names to ignore")
 	rootCmd.Flags().IntVarP(&flagHeadingLevel, "heading-level", "l", 1, "Markdown heading level (1-6)")
-	rootCmd.Flags().BoolVar(&flagIncludeHidden, "include-hidden", false, "Include hidden files and directories")
 	rootCmd.Flags().StringVarP(&flagFormat, "format", "f", "markdown", fmt.Sprintf("Output format: %s", formats))
-	rootCmd.Flags().StringVarP(&flagGitignore, "gitignore", "g", "", "Path to .gitignore file (default: auto-detect in base dir)")
-	rootCmd.Flags().BoolVar(&flagUseGitignore, "use-gitignore", true, "Automatically use .gitignore in base directory if present")
-	rootCmd.Flags().StringSliceVarP(&flagIgnorePatterns, "ignore-pattern", "p", nil, "Custom gitignore-style patterns to exclude (can be repeated)")
+	rootCmd.Flags().IntVar(&flagDiffContext, "diff-context", 3, "Context lines around each hunk with --format diff")
+	rootCmd.Flags().BoolVar(&flagDiffFull, "diff-full", false, "Include the full file after its diff with --format diff")
+	rootCmd.Flags().BoolVar(&flagTree, "tree", false, "Start the output with a directory tree of the included files (markdown, json and xml)")
+	rootCmd.Flags().StringVar(&flagTreeAnnotate, "tree-annotate", processor.TreePlain, fmt.Sprintf("What to show next to each file in --tree: %s", strings.Join(processor.TreeAnnotations, ", ")))
+	rootCmd.Flags().BoolVar(&flagTOC, "toc", false, "Start markdown output with a table of contents linking to each file")
+	rootCmd.Flags().BoolVar(&flagMeta, "meta", false, "Show each file's size, line count, modification time, permissions and SHA-256 hash")
+	rootCmd.Flags().BoolVar(&flagShowTokens, "show-tokens", false, "Report estimated token counts in total and per file")
+	rootCmd.Flags().BoolVar(&flagStats, "stats", false, "Report file, byte, line and token counts per extension, directory and language after writing")
+	rootCmd.Flags().StringVar(&flagStatsFormat, "stats-format", statsTable, fmt.Sprintf("Format of the --stats report: %s", strings.Join(statsFormats, ", ")))
+	rootCmd.Flags().IntVar(&flagMaxTokens, "max-tokens", 0, "Keep the output within this many tokens by dropping low priority files (0 = no limit)")
+	rootCmd.Flags().StringVar(&flagPriority, "priority", processor.OrderDepth, fmt.Sprintf("File priority under --max-tokens after READMEs and entrypoints: %s", strings.Join(processor.BudgetOrders, ", ")))
+	rootCmd.Flags().StringSliceVar(&flagPriorityGlobs, "priority-glob", nil, "Gitignore-style patterns for files to keep first under --max-tokens (can be repeated)")
+	rootCmd.Flags().BoolVar(&flagTruncate, "truncate", false, "Truncate the first file that does not fit under --max-tokens instead of dropping it")
+	rootCmd.Flags().StringVar(&flagSplitSize, "split-size", "", "Split output into parts of at most this size (e.g. 100K, 2M)")
+	rootCmd.Flags().IntVar(&flagSplitTokens, "split-tokens", 0, "Split output into parts of at most this many tokens")
+	addTokenizerFlags(rootCmd)
 
-	_ = rootCmd.MarkFlagRequired("ext")
+	rootCmd.MarkFlagsMutuallyExclusive("max-tokens", "split-size", "split-tokens")
+}
+
+// addSelectionFlags registers the flags that pick which files are read,
+// shared by the root command and stats so that both see the same files.
+func addSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagDir, "dir", "d", ".", "Root directory or .zip, .tar, .tar.gz or .tgz archive to scan")
+	cmd.Flags().StringSliceVarP(&flagExts, "ext", "e", nil, "File extension(s) to include, including compound ones like .d.ts (e.g. .rs,.py or repeat -e); required unless --name or --include is given")
+	cmd.Flags().StringSliceVar(&flagLangs, "lang", nil, "Language(s) to include by extension, file name and shebang (e.g. go,python)")
+	cmd.Flags().BoolVar(&flagShebang, "shebang", false, "Also include files without an extension whose #! line runs a language matching --ext")
+	cmd.Flags().StringSliceVar(&flagNames, "name", nil, "Exact file name(s) to include in addition to --ext (e.g. Makefile,Dockerfile,go.mod)")
+	cmd.Flags().StringSliceVarP(&flagIgnoreDirs, "ignore-dirs", "i", []string{".git", "node_modules", "vendor"}, "Directory names to ignore")
+	cmd.Flags().BoolVar(&flagIncludeHidden, "include-hidden", false, "Include hidden files and directories")
+	cmd.Flags().StringVarP(&flagGitignore, "gitignore", "g", "", "Path to an extra .gitignore file applied at the base directory")
+	cmd.Flags().BoolVar(&flagUseGitignore, "use-gitignore", true, "Apply every .gitignore in the scanned tree and its parent directories up to the repository root")
+	cmd.Flags().BoolVar(&flagGitExcludes, "git-excludes", true, "With --use-gitignore, also apply .git/info/exclude and the global core.excludesFile")
+	cmd.Flags().BoolVar(&flagGitTracked, "git-tracked", false, "Scan only the files tracked in the git index, even if they match a .gitignore")
+	cmd.Flags().StringVar(&flagRev, "rev", "", "Read files from this commit, branch or tag instead of the working tree")
+	cmd.Flags().StringVar(&flagChangedSince, "changed-since", "", "Only include files that differ from this commit, branch or tag, including uncommitted changes")
+	cmd.Flags().BoolVar(&flagStaged, "staged", false, "Only include files with staged changes")
+	cmd.Flags().BoolVar(&flagUnstaged, "unstaged", false, "Only include files with unstaged changes")
+	cmd.Flags().StringSliceVar(&flagIncludes, "include", nil, "Glob(s) of paths to include, relative to --dir, with ** for any depth (e.g. src/**/*.go); combined with --ext when both are given")
+	cmd.Flags().StringSliceVarP(&flagIgnorePatterns, "ignore-pattern", "p", nil, "Custom gitignore-style patterns to exclude (can be repeated)")
+	cmd.Flags().StringVar(&flagBinary, "binary", processor.BinarySkip, fmt.Sprintf("How to handle binary files: %s", strings.Join(processor.BinaryPolicies, ", ")))
+	cmd.Flags().StringVar(&flagMaxFileSize, "max-file-size", "", "Skip or truncate files larger than this (e.g. 100K, 1M)")
+	cmd.Flags().StringVar(&flagMaxTotalSize, "max-total-size", "", "Skip or truncate files once their total size reaches this (e.g. 10M)")
+	cmd.Flags().StringVar(&flagOversize, "oversize", processor.OversizeSkip, fmt.Sprintf("What to do with files over a size limit: %s", strings.Join(processor.OversizeModes, ", ")))
+	cmd.Flags().IntVarP(&flagJobs, "jobs", "j", 0, "Number of files to read concurrently (0 = one per CPU)")
+
+	cmd.MarkFlagsMutuallyExclusive("git-tracked", "rev", "staged")
+	cmd.MarkFlagsMutuallyExclusive("git-tracked", "rev", "unstaged")
+	cmd.MarkFlagsMutuallyExclusive("git-tracked", "changed-since")
+}
+
+func addTokenizerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagTokenizer, "tokenizer", tokenizer.DefaultName, fmt.Sprintf("Token estimator: %s; %s is a BPE trained on Go code that only approximates real counts", strings.Join(tokenizer.List(), ", "), tokenizer.EmbeddedBPEName))
+	cmd.Flags().StringVar(&flagTokenVocab, "token-vocab", "", "Path to a tiktoken rank file (e.g. cl100k_base.tiktoken) to count with in place of --tokenizer, for real BPE counts")
 }
 
 func run(cmd *cobra.Command, args []string) error {
@@ -74,73 +166,477 @@ func run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("%w\nAvailable formats: %s", err, strings.Join(registry.List(), ", "))
 	}
 
-	extSet, err := processExtensions(flagExts)
+	if !slices.Contains(processor.TreeAnnotations, flagTreeAnnotate) {
+		return fmt.Errorf("invalid --tree-annotate %q (expected one of %s)", flagTreeAnnotate, strings.Join(processor.TreeAnnotations, ", "))
+	}
+	if err := checkStatsFormat(); err != nil {
+		return err
+	}
+	if err := checkFormatFlags(proc.Name()); err != nil {
+		return err
+	}
+
+	sel, err := selectFiles()
+	if err != nil {
+		return err
+	}
+	defer sel.close()
+	baseDir, src, files, deleted, stage := sel.baseDir, sel.src, sel.files, sel.deleted, sel.stage
+
+	if len(files) == 0 && len(deleted) == 0 {
+		printSummary("No files found matching criteria", stage.skipped)
+		return nil
+	}
+
+	opts := processor.Options{
+		BaseDir:      baseDir,
+		HeadingLevel: flagHeadingLevel,
+		Extensions:   sortedKeys(sel.extSet),
+		GeneratedAt:  time.Now().UTC(),
+		Deleted:      deleted,
+		Base:         src.base,
+		BinaryPolicy: flagBinary,
+		DiffContext:  flagDiffContext,
+		DiffFull:     flagDiffFull,
+		Tree:         flagTree,
+		TreeAnnotate: flagTreeAnnotate,
+		TOC:          flagTOC,
+		Meta:         flagMeta,
+	}
+	splitBytes, err := parseSize(flagSplitSize)
+	if err != nil {
+		return fmt.Errorf("invalid --split-size: %w", err)
+	}
+
+	var enc tokenizer.Encoder
+	if flagShowTokens || flagStats || flagMaxTokens > 0 || flagSplitTokens > 0 {
+		enc, err = loadTokenizer(flagTokenizer, flagTokenVocab)
+		if err != nil {
+			return err
+		}
+	}
+
+	outPath := flagOut
+	if outPath == "" {
+		outPath = "concat" + proc.FileExtension()
+	}
+
+	// Without splitting or token counting the whole bundle is never needed
+	// at once, so files are read and written one at a time.
+	if splitBytes == 0 && flagSplitTokens == 0 && flagMaxTokens == 0 && !flagShowTokens {
+		seq := stage.apply(processor.LoadFilesSeq(src.fsys, files, baseDir, flagJobs, flagMeta))
+		var stats *processor.Stats
+		if flagStats {
+			stats = processor.NewStats(enc)
+			seq = countFiles(seq, stats)
+		}
+		err := streamOutput(outPath, stage, func(w io.Writer) error {
+			if err := processor.ProcessTo(proc, w, seq, opts); err != nil {
+				return err
+			}
+			return stage.err
+		})
+		if err != nil || stats == nil {
+			return err
+		}
+		return printStats(os.Stderr, stats.Report(flagStatsTop), flagStatsFormat)
+	}
+
+	loaded, err := processor.LoadFilesParallel(src.fsys, files, baseDir, flagJobs, flagMeta)
+	if err != nil {
+		return fmt.Errorf("loading files: %w", err)
+	}
+	fileInfos := slices.Collect(stage.apply(slices.Values(loaded)))
+	if stage.err != nil {
+		return stage.err
+	}
+	if splitBytes > 0 || flagSplitTokens > 0 {
+		limits := processor.SplitLimits{MaxBytes: int(splitBytes), MaxTokens: flagSplitTokens}
+		parts, err := processor.Split(proc, fileInfos, opts, limits, enc)
+		if err != nil {
+			return fmt.Errorf("splitting output: %w", err)
+		}
+		if err := writeParts(parts, outPath, stage.skipped); err != nil {
+			return err
+		}
+		if flagShowTokens {
+			printTokenReport(os.Stderr, processor.CountTokens(fileInfos, enc), countPartTokens(parts, enc))
+		}
+		if flagStats {
+			return printFileStats(fileInfos, enc)
+		}
+		return nil
+	}
+
+	var (
+		content []byte
+		omitted []processor.OmittedFile
+		written = fileInfos
+	)
+	if flagMaxTokens > 0 {
+		budget := processor.Budget{
+			MaxTokens: flagMaxTokens,
+			Order:     flagPriority,
+			Priority:  flagPriorityGlobs,
+			Truncate:  flagTruncate,
+		}
+		content, written, omitted, err = processor.ProcessWithBudget(proc, fileInfos, opts, budget, enc)
+	} else {
+		content, err = proc.Process(fileInfos, opts)
+	}
 	if err != nil {
+		return fmt.Errorf("processing files: %w", err)
+	}
+
+	if err := writeOutput(content, outPath, len(written), stage.skipped); err != nil {
 		return err
 	}
 
+	if len(omitted) > 0 {
+		fmt.Fprintf(os.Stderr, "Omitted or truncated %d file(s) to stay within %d tokens\n", len(omitted), flagMaxTokens)
+	}
+
+	if flagShowTokens {
+		report := processor.CountTokens(fileInfos, enc)
+		printTokenReport(os.Stderr, report, enc.Count(content))
+	}
+	if flagStats {
+		return printFileStats(written, enc)
+	}
+
+	return nil
+}
+
+// selection is the set of files picked by the selection flags, with the
+// source they are read from and the stage they are loaded through.
+type selection struct {
+	baseDir string
+	extSet  map[string]struct{}
+	src     source
+	files   []string
+	deleted []string
+	stage   *loadStage
+}
+
+func (s *selection) close() {
+	if s.src.closer != nil {
+		s.src.closer.Close()
+	}
+}
+
+// selectFiles validates the selection flags, builds the filter chain and
+// scans for the files it lets through. The caller must close the result.
+func selectFiles() (*selection, error) {
+	if len(flagExts) == 0 && len(flagLangs) == 0 && len(flagNames) == 0 && len(flagIncludes) == 0 {
+		return nil, errors.New("at least one --ext, --lang, --name or --include is required")
+	}
+
+	extSet := make(map[string]struct{})
+	if len(flagExts) > 0 {
+		var err error
+		extSet, err = processExtensions(flagExts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	names := processNames(flagNames)
+	langs, err := processLanguages(flagLangs, extSet, names)
+	if err != nil {
+		return nil, err
+	}
+	if flagShebang {
+		addShebangLanguages(langs, extSet)
+	}
+
+	if !slices.Contains(processor.BinaryPolicies, flagBinary) {
+		return nil, fmt.Errorf("invalid --binary %q (expected one of %s)", flagBinary, strings.Join(processor.BinaryPolicies, ", "))
+	}
+
+	sizeLimits, err := parseSizeLimits()
+	if err != nil {
+		return nil, err
+	}
+
 	ignoreSet := processIgnoreDirs(flagIgnoreDirs)
 	baseDir := filepath.Clean(flagDir)
 
-	gitignorePath := flagGitignore
-	if gitignorePath == "" && flagUseGitignore {
-		gitignorePath = filter.AutoDetectGitignore(baseDir)
-		if gitignorePath != "" {
-			fmt.Fprintf(os.Stderr, "Using .gitignore: %s\n", gitignorePath)
-		}
+	if flagGitignore != "" {
+		fmt.Fprintf(os.Stderr, "Using .gitignore: %s\n", flagGitignore)
 	}
 
 	filterCfg := filter.Config{
-		Extensions:     extSet,
-		IgnoreDirs:     ignoreSet,
-		IncludeHidden:  flagIncludeHidden,
-		GitignorePath:  gitignorePath,
-		CustomPatterns: flagIgnorePatterns,
-		BaseDir:        baseDir,
+		Extensions:      extSet,
+		Names:           names,
+		Languages:       langs,
+		IgnoreDirs:      ignoreSet,
+		IncludeHidden:   flagIncludeHidden,
+		GitignorePath:   flagGitignore,
+		NestedGitignore: flagUseGitignore,
+		GitExcludes:     flagGitExcludes,
+		CustomPatterns:  flagIgnorePatterns,
+		IncludePatterns: flagIncludes,
+		BaseDir:         baseDir,
 	}
 
-	filterChain, err := filter.BuildChain(filterCfg)
+	src, err := openSource(baseDir, &filterCfg)
 	if err != nil {
-		return fmt.Errorf("building filter chain: %w", err)
+		return nil, err
 	}
+	filterCfg.FilesFS = src.fsys
+	sel := &selection{baseDir: baseDir, extSet: extSet, src: src}
 
-	s := scanner.New(baseDir, filterChain)
-	files, err := s.Scan()
+	filterChain, err := filter.BuildChain(filterCfg)
 	if err != nil {
-		return fmt.Errorf("scanning files: %w", err)
+		sel.close()
+		return nil, fmt.Errorf("building filter chain: %w", err)
 	}
 
-	if len(files) == 0 {
-		fmt.Fprintln(os.Stderr, "No files found matching criteria")
-		return nil
+	// Files over --max-file-size are skipped before they are read; in
+	// truncate mode they are read and cut down by the load stage instead.
+	var sizeFilter *filter.SizeFilter
+	if sizeLimits.MaxFileSize > 0 && sizeLimits.Mode == processor.OversizeSkip {
+		sizeFilter = filter.NewSizeFilter(sizeLimits.MaxFileSize)
+		filterChain.Add(sizeFilter)
 	}
 
-	fileInfos, err := processor.LoadFiles(files, baseDir)
+	s := scanner.NewWithSource(baseDir, src.walk, filterChain)
+	sel.files, err = s.Scan()
 	if err != nil {
-		return fmt.Errorf("loading files: %w", err)
+		sel.close()
+		return nil, fmt.Errorf("scanning files: %w", err)
 	}
 
-	opts := processor.Options{
-		BaseDir:      baseDir,
-		HeadingLevel: flagHeadingLevel,
+	sel.stage = &loadStage{binaryPolicy: flagBinary, sizes: processor.NewSizeBudget(sizeLimits)}
+	if sizeFilter != nil {
+		for _, path := range sizeFilter.Skipped {
+			sel.stage.skip(filter.RelPath(path, baseDir), "over the "+processor.LimitFileSize+" limit", false)
+		}
+	}
+
+	for _, rel := range src.deleted {
+		if filter.IncludesPath(filterChain, baseDir, rel) {
+			sel.deleted = append(sel.deleted, filepath.FromSlash(rel))
+		}
+	}
+
+	return sel, nil
+}
+
+type source struct {
+	walk    scanner.Source
+	fsys    fs.FS
+	base    fs.FS
+	deleted []string
+	closer  io.Closer
+}
+
+// openSource picks where the scanned files come from: the working tree, an
+// archive, the git index, a revision's tree or a set of changed files, and
+// the file system to load their contents from. For archives and revisions
+// it points the gitignore filter at that file system. The diff format
+// compares against --changed-since, or HEAD when that is not given.
+func openSource(baseDir string, cfg *filter.Config) (source, error) {
+	gitMode := flagGitTracked || flagRev != "" || flagChangedSince != "" || flagStaged || flagUnstaged
+	if info, err := os.Stat(baseDir); err == nil && !info.IsDir() && scanner.IsArchive(baseDir) {
+		if gitMode || flagFormat == "diff" {
+			return source{}, fmt.Errorf("%s: git options cannot be used with an archive", baseDir)
+		}
+		fsys, closer, err := scanner.OpenArchive(baseDir)
+		if err != nil {
+			return source{}, err
+		}
+		cfg.SourceFS = fsys
+		cfg.SourceSubdir = "."
+		cfg.GitExcludes = false
+		return source{walk: scanner.FSSource(fsys, baseDir), fsys: fsys, closer: closer}, nil
+	}
+
+	diff := flagFormat == "diff"
+	since := flagChangedSince
+	if diff && since == "" && !flagStaged && !flagUnstaged && !flagGitTracked {
+		since = "HEAD"
+	}
+
+	changes := since != "" || flagStaged || flagUnstaged
+	if !flagGitTracked && flagRev == "" && !changes {
+		return source{walk: scanner.DirSource(baseDir), fsys: os.DirFS(baseDir)}, nil
 	}
-	content, err := proc.Process(fileInfos, opts)
+
+	repo, err := vcs.Open(baseDir)
 	if err != nil {
-		return fmt.Errorf("processing files: %w", err)
+		return source{}, err
+	}
+	subdir, err := repo.Prefix(baseDir)
+	if err != nil {
+		return source{}, err
 	}
 
-	outPath := flagOut
-	if outPath == "" {
-		outPath = "concat" + proc.FileExtension()
+	var src source
+	if diff {
+		baseRev := since
+		if baseRev == "" {
+			baseRev = "HEAD"
+		}
+		if _, src.base, err = subTree(repo, baseRev, subdir); err != nil {
+			return source{}, err
+		}
 	}
 
-	if err := writeOutput(content, outPath, len(files)); err != nil {
-		return err
+	if flagRev != "" {
+		tree, fsys, err := subTree(repo, flagRev, subdir)
+		if err != nil {
+			return source{}, err
+		}
+		src.fsys = fsys
+		if since == "" {
+			cfg.SourceFS = tree
+			cfg.SourceSubdir = subdir
+			src.walk = scanner.FSSource(fsys, baseDir)
+			return src, nil
+		}
+
+		set, err := repo.ChangedBetween(baseDir, since, flagRev)
+		if err != nil {
+			return source{}, err
+		}
+		cfg.NestedGitignore = false
+		src.walk = scanner.FSPathSource(fsys, baseDir, set.Modified)
+		src.deleted = set.Deleted
+		return src, nil
 	}
 
+	// Tracked files are wanted even when a .gitignore matches them.
+	cfg.NestedGitignore = false
+
+	if flagGitTracked {
+		tracked, err := repo.TrackedFiles(baseDir)
+		if err != nil {
+			return source{}, err
+		}
+		src.walk = scanner.PathSource(baseDir, tracked)
+		src.fsys = os.DirFS(baseDir)
+		return src, nil
+	}
+
+	var set vcs.Changes
+	if since != "" {
+		set, err = repo.ChangedSince(baseDir, since)
+		if err != nil {
+			return source{}, err
+		}
+	}
+	for _, pick := range []struct {
+		enabled bool
+		list    func(string) (vcs.Changes, error)
+	}{{flagStaged, repo.Staged}, {flagUnstaged, repo.Unstaged}} {
+		if !pick.enabled {
+			continue
+		}
+		c, err := pick.list(baseDir)
+		if err != nil {
+			return source{}, err
+		}
+		set = mergeChanges(set, c)
+	}
+
+	src.walk = scanner.PathSource(baseDir, set.Modified)
+	src.fsys = os.DirFS(baseDir)
+	src.deleted = set.Deleted
+	return src, nil
+}
+
+// formatFlags lists the output flags that only some formats honour, so that
+// they are refused rather than silently ignored by the others.
+var formatFlags = []struct {
+	name    string
+	set     func() bool
+	formats []string
+}{
+	{"tree", func() bool { return flagTree }, []string{"markdown", "json", "xml"}},
+	{"toc", func() bool { return flagTOC }, []string{"markdown"}},
+	{"meta", func() bool { return flagMeta }, []string{"markdown", "json", "xml"}},
+	{"diff-full", func() bool { return flagDiffFull }, []string{"diff"}},
+}
+
+func checkFormatFlags(format string) error {
+	for _, f := range formatFlags {
+		if f.set() && !slices.Contains(f.formats, format) {
+			return fmt.Errorf("--%s is not supported with --format %s (only with %s)", f.name, format, strings.Join(f.formats, ", "))
+		}
+	}
 	return nil
 }
 
+// subTree returns the tree of rev and its subdirectory subdir.
+func subTree(repo *vcs.Repo, rev, subdir string) (fs.FS, fs.FS, error) {
+	tree, err := repo.TreeFS(rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	sub, err := fs.Sub(tree, subdir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tree, sub, nil
+}
+
+// mergeChanges combines two change sets. When a path appears in both, b
+// wins, so unstaged changes describe the work tree better than staged ones.
+func mergeChanges(a, b vcs.Changes) vcs.Changes {
+	deleted := make(map[string]bool)
+	for _, c := range []vcs.Changes{a, b} {
+		for _, p := range c.Modified {
+			deleted[p] = false
+		}
+		for _, p := range c.Deleted {
+			deleted[p] = true
+		}
+	}
+
+	var out vcs.Changes
+	for p, gone := range deleted {
+		if gone {
+			out.Deleted = append(out.Deleted, p)
+		} else {
+			out.Modified = append(out.Modified, p)
+		}
+	}
+	sort.Strings(out.Modified)
+	sort.Strings(out.Deleted)
+	return out
+}
+
+// loadTokenizer returns the encoder called name, or a BPE read from
+// vocabPath when one is given, which takes the place of name.
+func loadTokenizer(name, vocabPath string) (tokenizer.Encoder, error) {
+	if vocabPath == "" {
+		return tokenizer.Get(name)
+	}
+
+	f, err := os.Open(vocabPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening token vocabulary: %w", err)
+	}
+	defer f.Close()
+
+	enc, err := tokenizer.LoadBPE(filepath.Base(vocabPath), f)
+	if err != nil {
+		return nil, fmt.Errorf("loading token vocabulary: %w", err)
+	}
+	return enc, nil
+}
+
+func printTokenReport(w io.Writer, report processor.TokenReport, outputTokens int) {
+	fmt.Fprintf(w, "Tokens (%s): %d in files, %d in output\n", report.Encoder, report.Total, outputTokens)
+	for _, f := range report.Largest(0) {
+		share := 0.0
+		if report.Total > 0 {
+			share = 100 * float64(f.Tokens) / float64(report.Total)
+		}
+		fmt.Fprintf(w, "  %8d  %5.1f%%  %s\n", f.Tokens, share, filepath.ToSlash(f.RelPath))
+	}
+}
+
 func processExtensions(rawExts []string) (map[string]struct{}, error) {
 	extSet := make(map[string]struct{})
 	for _, raw := range rawExts {
@@ -158,6 +654,55 @@ func processExtensions(rawExts []string) (map[string]struct{}, error) {
 	return extSet, nil
 }
 
+func processNames(rawNames []string) map[string]struct{} {
+	names := make(map[string]struct{}, len(rawNames))
+	for _, raw := range rawNames {
+		for _, name := range handleCommaSeparatedValues(raw) {
+			names[name] = struct{}{}
+		}
+	}
+	return names
+}
+
+// processLanguages resolves language names against the lang catalogue,
+// adding their extensions and file names to extSet and names. It returns
+// the set of languages whose scripts may also be recognised by shebang.
+func processLanguages(rawLangs []string, extSet, names map[string]struct{}) (map[string]struct{}, error) {
+	langs := make(map[string]struct{})
+	for _, raw := range rawLangs {
+		for _, tok := range handleCommaSeparatedValues(raw) {
+			l, ok := lang.Lookup(tok)
+			if !ok {
+				return nil, fmt.Errorf("unknown language %q (known: %s)", tok, strings.Join(lang.Names(), ", "))
+			}
+			langs[l.Name] = struct{}{}
+			for _, ext := range l.Extensions {
+				extSet[ext] = struct{}{}
+			}
+			for _, name := range l.Filenames {
+				names[name] = struct{}{}
+			}
+		}
+	}
+	return langs, nil
+}
+
+// addShebangLanguages adds to langs every language that has one of the
+// extensions in extSet and is known to run as a script.
+func addShebangLanguages(langs, extSet map[string]struct{}) {
+	for _, l := range lang.All() {
+		if len(l.Interpreters) == 0 {
+			continue
+		}
+		for _, ext := range l.Extensions {
+			if _, ok := extSet[ext]; ok {
+				langs[l.Name] = struct{}{}
+				break
+			}
+		}
+	}
+}
+
 func processIgnoreDirs(rawIgnore []string) map[string]struct{} {
 	ignoreSet := make(map[string]struct{}, len(rawIgnore))
 	for _, d := range rawIgnore {
@@ -169,20 +714,260 @@ func processIgnoreDirs(rawIgnore []string) map[string]struct{} {
 	return ignoreSet
 }
 
-func writeOutput(content []byte, outPath string, fileCount int) error {
+func writeParts(parts []processor.Part, outPath string, skipped []skippedFile) error {
 	if outPath == "-" {
-		_, err := os.Stdout.Write(content)
-		return err
+		return errors.New("split output cannot be written to stdout; use --out to name the parts")
+	}
+
+	for _, part := range parts {
+		if err := writeOutput(part.Content, processor.PartPath(outPath, part.Index), len(part.Files), nil); err != nil {
+			return err
+		}
+	}
+	printSummary("", skipped)
+	return nil
+}
+
+func countPartTokens(parts []processor.Part, enc tokenizer.Encoder) int {
+	total := 0
+	for _, part := range parts {
+		total += enc.Count(part.Content)
+	}
+	return total
+}
+
+// writeOutput writes content to outPath, or stdout for "-", and reports how
+// many files it holds along with the files that were skipped on the way.
+func writeOutput(content []byte, outPath string, fileCount int, skipped []skippedFile) error {
+	if outPath == "-" {
+		if _, err := os.Stdout.Write(content); err != nil {
+			return err
+		}
+		printSummary("", skipped)
+		return nil
 	}
 
 	if err := os.WriteFile(outPath, content, 0o644); err != nil {
 		return fmt.Errorf("write output: %w", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "Wrote %d file(s) to %s\n", fileCount, outPath)
+	printSummary(fmt.Sprintf("Wrote %d file(s) to %s", fileCount, outPath), skipped)
 	return nil
 }
 
+// streamOutput is writeOutput for output produced incrementally by write
+// from the files passing through stage. The output goes to a temporary file
+// that replaces outPath only once complete, so that a previous bundle being
+// read back in as input is never seen half written.
+func streamOutput(outPath string, stage *loadStage, write func(io.Writer) error) error {
+	if outPath == "-" {
+		if err := write(os.Stdout); err != nil {
+			return fmt.Errorf("processing files: %w", err)
+		}
+		printSummary("", stage.skipped)
+		return nil
+	}
+
+	f, err := createOutputTemp(outPath)
+	if err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("processing files: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	if err := os.Rename(f.Name(), outPath); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	printSummary(fmt.Sprintf("Wrote %d file(s) to %s", stage.kept, outPath), stage.skipped)
+	return nil
+}
+
+// createOutputTemp creates an empty file next to outPath with the mode
+// os.WriteFile would leave outPath with: that of the file it replaces, or
+// 0644 less the umask for a new one.
+func createOutputTemp(outPath string) (*os.File, error) {
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(outPath); err == nil && info.Mode().IsRegular() {
+		perm = info.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(outPath)
+	for {
+		name := filepath.Join(dir, fmt.Sprintf(".%s.%d.tmp", base, rand.Uint32()))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return f, err
+	}
+}
+
+// skippedFile is a file left out of the output, or cut short, before it
+// was rendered.
+type skippedFile struct {
+	RelPath   string
+	Reason    string
+	Truncated bool
+}
+
+// printSummary prints line, if any, extended with a count of the skipped
+// and truncated files, then lists them.
+func printSummary(line string, skipped []skippedFile) {
+	if len(skipped) > 0 {
+		var counts []string
+		if n := len(skipped) - countTruncated(skipped); n > 0 {
+			counts = append(counts, fmt.Sprintf("skipped %d", n))
+		}
+		if n := countTruncated(skipped); n > 0 {
+			counts = append(counts, fmt.Sprintf("truncated %d", n))
+		}
+		summary := strings.Join(counts, ", ") + " file(s):"
+		if line == "" {
+			line = strings.ToUpper(summary[:1]) + summary[1:]
+		} else {
+			line += "; " + summary
+		}
+	}
+	if line != "" {
+		fmt.Fprintln(os.Stderr, line)
+	}
+
+	skipped = slices.Clone(skipped)
+	slices.SortStableFunc(skipped, func(a, b skippedFile) int { return strings.Compare(a.RelPath, b.RelPath) })
+	for _, f := range skipped {
+		action := "skipped"
+		if f.Truncated {
+			action = "truncated"
+		}
+		fmt.Fprintf(os.Stderr, "  %s %s: %s\n", action, f.RelPath, f.Reason)
+	}
+}
+
+func countTruncated(skipped []skippedFile) int {
+	n := 0
+	for _, f := range skipped {
+		if f.Truncated {
+			n++
+		}
+	}
+	return n
+}
+
+// loadStage runs between loading files and rendering them. It warns about
+// files that could not be read and applies the binary file policy and the
+// size limits, remembering the files it skips or truncates.
+type loadStage struct {
+	binaryPolicy string
+	sizes        *processor.SizeBudget
+	skipped      []skippedFile
+	kept         int
+	err          error
+}
+
+func (l *loadStage) apply(files iter.Seq[processor.FileInfo]) iter.Seq[processor.FileInfo] {
+	return func(yield func(processor.FileInfo) bool) {
+		for f := range files {
+			if f.Err != nil {
+				fmt.Fprintf(os.Stderr, "warn: could not read %s: %v\n", f.RelPath, f.Err)
+			}
+
+			f, keep, err := processor.HandleBinary(f, l.binaryPolicy)
+			if err != nil {
+				l.err = err
+				return
+			}
+			if !keep {
+				l.skip(f.RelPath, "binary", false)
+				continue
+			}
+
+			f, exceeded, keep := l.sizes.Apply(f)
+			if !keep {
+				l.skip(f.RelPath, "over the "+exceeded+" limit", false)
+				continue
+			}
+			if exceeded != "" {
+				l.skip(f.RelPath, "cut to the "+exceeded+" limit", true)
+			}
+
+			l.kept++
+			if !yield(f) {
+				return
+			}
+		}
+	}
+}
+
+func (l *loadStage) skip(relPath, reason string, truncated bool) {
+	l.skipped = append(l.skipped, skippedFile{RelPath: filepath.ToSlash(relPath), Reason: reason, Truncated: truncated})
+}
+
+func parseSizeLimits() (processor.SizeLimits, error) {
+	limits := processor.SizeLimits{Mode: flagOversize}
+	if !slices.Contains(processor.OversizeModes, flagOversize) {
+		return limits, fmt.Errorf("invalid --oversize %q (expected one of %s)", flagOversize, strings.Join(processor.OversizeModes, ", "))
+	}
+
+	var err error
+	if limits.MaxFileSize, err = parseSize(flagMaxFileSize); err != nil {
+		return limits, fmt.Errorf("invalid --max-file-size: %w", err)
+	}
+	if limits.MaxTotalSize, err = parseSize(flagMaxTotalSize); err != nil {
+		return limits, fmt.Errorf("invalid --max-total-size: %w", err)
+	}
+	return limits, nil
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseSize parses a byte count with an optional K, M or G suffix (powers
+// of 1024, with an optional trailing B or iB). An empty string is zero, for
+// no limit; anything else must come to at least one byte.
+func parseSize(arg string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(arg))
+	if s == "" {
+		return 0, nil
+	}
+
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("invalid size %q", arg)
+	}
+	size := n * float64(multiplier)
+	if size < 1 || size >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: must be at least 1 byte and below 8 EiB", arg)
+	}
+	return int64(size), nil
+}
+
 func handleCommaSeparatedValues(s string) []string {
 	var out []string
 	for _, part := range strings.Split(s, ",") {
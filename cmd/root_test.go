@@ -0,0 +1,897 @@
+package cmd
+
+import (
+	"amalgo/processor"
+	"amalgo/tokenizer"
+	"amalgo/vcs"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestProcessExtensions(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     []string
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name:      "single extension",
+			input:     []string{".go"},
+			wantCount: 1,
+			wantErr:   false,
+		},
+		{
+			name:      "multiple extensions",
+			input:     []string{".go", ".rs", ".py"},
+			wantCount: 3,
+			wantErr:   false,
+		},
+		{
+			name:      "comma separated",
+			input:     []string{".go,.rs,.py"},
+			wantCount: 3,
+			wantErr:   false,
+		},
+		{
+			name:      "mixed format",
+			input:     []string{".go,.rs", ".py"},
+			wantCount: 3,
+			wantErr:   false,
+		},
+		{
+			name:      "without leading dot",
+			input:     []string{"go", "rs"},
+			wantCount: 2,
+			wantErr:   false,
+		},
+		{
+			name:      "case insensitive",
+			input:     []string{".GO", ".Rs", ".PY"},
+			wantCount: 3,
+			wantErr:   false,
+		},
+		{
+			name:      "duplicate extensions",
+			input:     []string{".go", ".go", ".go"},
+			wantCount: 1,
+			wantErr:   false,
+		},
+		{
+			name:      "empty string",
+			input:     []string{""},
+			wantCount: 0,
+			wantErr:   true,
+		},
+		{
+			name:      "whitespace only",
+			input:     []string{"  ", "\t"},
+			wantCount: 0,
+			wantErr:   true,
+		},
+		{
+			name:      "with spaces",
+			input:     []string{" .go ", " .rs "},
+			wantCount: 2,
+			wantErr:   false,
+		},
+		{
+			name:      "compound suffixes",
+			input:     []string{".d.ts,.test.js", ".go"},
+			wantCount: 3,
+			wantErr:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := processExtensions(tt.input)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(result) != tt.wantCount {
+				t.Errorf("expected %d extensions, got %d", tt.wantCount, len(result))
+			}
+		})
+	}
+}
+
+func TestProcessIgnoreDirs(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     []string
+		wantCount int
+		contains  []string
+	}{
+		{
+			name:      "single directory",
+			input:     []string{"node_modules"},
+			wantCount: 1,
+			contains:  []string{"node_modules"},
+		},
+		{
+			name:      "multiple directories",
+			input:     []string{"node_modules", "vendor", ".git"},
+			wantCount: 3,
+			contains:  []string{"node_modules", "vendor", ".git"},
+		},
+		{
+			name:      "with whitespace",
+			input:     []string{" node_modules ", " vendor "},
+			wantCount: 2,
+			contains:  []string{"node_modules", "vendor"},
+		},
+		{
+			name:      "empty strings filtered",
+			input:     []string{"", "node_modules", "  ", "vendor"},
+			wantCount: 2,
+			contains:  []string{"node_modules", "vendor"},
+		},
+		{
+			name:      "all empty",
+			input:     []string{"", "  ", "\t"},
+			wantCount: 0,
+			contains:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := processIgnoreDirs(tt.input)
+
+			if len(result) != tt.wantCount {
+				t.Errorf("expected %d directories, got %d", tt.wantCount, len(result))
+			}
+
+			for _, dir := range tt.contains {
+				if _, ok := result[dir]; !ok {
+					t.Errorf("expected result to contain '%s'", dir)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessNames(t *testing.T) {
+	got := processNames([]string{"Makefile, Dockerfile", "go.mod", " ", "Makefile"})
+	want := map[string]struct{}{"Makefile": {}, "Dockerfile": {}, "go.mod": {}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestProcessLanguages(t *testing.T) {
+	extSet := map[string]struct{}{".md": {}}
+	names := map[string]struct{}{}
+
+	langs, err := processLanguages([]string{"Python,makefile"}, extSet, names)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := map[string]struct{}{"python": {}, "makefile": {}}; !reflect.DeepEqual(langs, want) {
+		t.Errorf("expected languages %v, got %v", want, langs)
+	}
+	for _, ext := range []string{".md", ".py", ".pyi", ".mk"} {
+		if _, ok := extSet[ext]; !ok {
+			t.Errorf("expected %s in extensions, got %v", ext, extSet)
+		}
+	}
+	if _, ok := names["Makefile"]; !ok {
+		t.Errorf("expected Makefile in names, got %v", names)
+	}
+
+	if _, err := processLanguages([]string{"cobol"}, extSet, names); err == nil {
+		t.Error("expected error for unknown language")
+	}
+}
+
+func TestAddShebangLanguages(t *testing.T) {
+	langs := map[string]struct{}{"ruby": {}}
+	addShebangLanguages(langs, map[string]struct{}{".py": {}, ".sh": {}, ".go": {}})
+
+	want := map[string]struct{}{"ruby": {}, "python": {}, "shell": {}}
+	if !reflect.DeepEqual(langs, want) {
+		t.Errorf("expected %v, got %v", want, langs)
+	}
+}
+
+func TestHandleCommaSeparatedValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "single value",
+			input:    "value",
+			expected: []string{"value"},
+		},
+		{
+			name:     "multiple values",
+			input:    "a,b,c",
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "with spaces",
+			input:    " a , b , c ",
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "empty parts",
+			input:    "a,,b,,,c",
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "empty string",
+			input:    "",
+			expected: []string{},
+		},
+		{
+			name:     "only commas",
+			input:    ",,,",
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := handleCommaSeparatedValues(tt.input)
+
+			if len(result) != len(tt.expected) {
+				t.Errorf("expected %d values, got %d", len(tt.expected), len(result))
+				return
+			}
+
+			for i, v := range result {
+				if v != tt.expected[i] {
+					t.Errorf("at index %d: expected '%s', got '%s'", i, tt.expected[i], v)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeExt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "with leading dot",
+			input:    ".go",
+			expected: ".go",
+		},
+		{
+			name:     "without leading dot",
+			input:    "go",
+			expected: ".go",
+		},
+		{
+			name:     "uppercase",
+			input:    ".GO",
+			expected: ".go",
+		},
+		{
+			name:     "mixed case",
+			input:    ".Go",
+			expected: ".go",
+		},
+		{
+			name:     "with spaces",
+			input:    "  .rs  ",
+			expected: ".rs",
+		},
+		{
+			name:     "empty string",
+			input:    "",
+			expected: "",
+		},
+		{
+			name:     "whitespace only",
+			input:    "   ",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := normalizeExt(tt.input)
+			if result != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"1024", 1024, false},
+		{"100K", 100 << 10, false},
+		{"100kb", 100 << 10, false},
+		{"2M", 2 << 20, false},
+		{"1.5MiB", 3 << 19, false},
+		{"1G", 1 << 30, false},
+		{"abc", 0, true},
+		{"-5", 0, true},
+		{"0", 0, true},
+		{"0.1", 0, true},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+		{"-inf", 0, true},
+		{"infK", 0, true},
+		{"1e30G", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseSize(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %d", result)
+				} else if !strings.Contains(err.Error(), fmt.Sprintf("%q", tt.input)) {
+					t.Errorf("expected error to quote the input %q, got %v", tt.input, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMergeChanges(t *testing.T) {
+	staged := vcs.Changes{Modified: []string{"a.go", "b.go"}, Deleted: []string{"c.go"}}
+	unstaged := vcs.Changes{Modified: []string{"c.go", "d.go"}, Deleted: []string{"b.go"}}
+
+	merged := mergeChanges(staged, unstaged)
+
+	expected := vcs.Changes{Modified: []string{"a.go", "c.go", "d.go"}, Deleted: []string{"b.go"}}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected %+v, got %+v", expected, merged)
+	}
+}
+
+func TestPrintStats(t *testing.T) {
+	report := processor.StatsReport{
+		Encoder:     "heuristic",
+		Total:       processor.StatsRow{Name: "total", Files: 2, Bytes: 300, Lines: 12, Tokens: 75},
+		Extensions:  []processor.StatsRow{{Name: ".go", Files: 2, Bytes: 300, Lines: 12, Tokens: 75}},
+		Directories: []processor.StatsRow{{Name: "cmd", Files: 1, Bytes: 200, Lines: 8, Tokens: 50}, {Name: ".", Files: 1, Bytes: 100, Lines: 4, Tokens: 25}},
+		Languages:   []processor.StatsRow{{Name: "go", Files: 2, Bytes: 300, Lines: 12, Tokens: 75}},
+		Largest:     []processor.FileStats{{Path: "cmd/root.go", Bytes: 200, Lines: 8, Tokens: 50}},
+	}
+
+	var buf bytes.Buffer
+	if err := printStats(&buf, report, statsTable); err != nil {
+		t.Fatalf("printStats failed: %v", err)
+	}
+	want := `2 file(s), 300 bytes, 12 lines, 75 tokens (heuristic)
+
+EXTENSION  FILES  BYTES  LINES  TOKENS
+.go        2      300    12     75
+
+DIRECTORY  FILES  BYTES  LINES  TOKENS
+cmd        1      200    8      50
+.          1      100    4      25
+
+LANGUAGE  FILES  BYTES  LINES  TOKENS
+go        2      300    12     75
+
+LARGEST FILES  BYTES  LINES  TOKENS
+cmd/root.go    200    8      50
+`
+	if buf.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	t.Run("write to file", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		outPath := filepath.Join(tmpDir, "output.md")
+
+		content := []byte("# Test Content\n")
+		err := writeOutput(content, outPath, 5, nil)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		data, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatalf("failed to read output file: %v", err)
+		}
+
+		if !bytes.Equal(data, content) {
+			t.Errorf("file content mismatch")
+		}
+	})
+
+	t.Run("write to stdout", func(t *testing.T) {
+
+		oldStdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+
+		content := []byte("test content")
+		err := writeOutput(content, "-", 1, nil)
+
+		os.Stdout = oldStdout
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err = w.Close()
+
+		if err != nil {
+			t.Fatalf("Failed to close file: %v", err)
+		}
+
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(r)
+
+		if err != nil {
+			t.Fatalf("Failed to read from buffer: %v", err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), content) {
+			t.Errorf("stdout content mismatch")
+		}
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		content := []byte("test")
+		err := writeOutput(content, "/nonexistent/path/file.md", 1, nil)
+
+		if err == nil {
+			t.Error("expected error for invalid path")
+		}
+	})
+}
+
+func TestStreamOutput(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.md")
+	if err := os.WriteFile(outPath, []byte("old"), 0o600); err != nil {
+		t.Fatalf("failed to create output: %v", err)
+	}
+
+	err := streamOutput(outPath, &loadStage{}, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("streamOutput failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("expected new content, got %q (%v)", data, err)
+	}
+	info, err := os.Stat(outPath)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the replaced file's mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(outPath)); len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left, got %d entries", len(entries))
+	}
+}
+
+func TestRun_Integration(t *testing.T) {
+
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"main.go":   "package main\n",
+		"util.go":   "package util\n",
+		"README.md": "# README\n",
+		"test.txt":  "text file\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	t.Run("successful run", func(t *testing.T) {
+
+		flagDir = tmpDir
+		flagExts = []string{".go"}
+		flagOut = filepath.Join(tmpDir, "output.md")
+		flagIgnoreDirs = []string{}
+		flagHeadingLevel = 1
+		flagIncludeHidden = false
+		flagFormat = "markdown"
+		flagGitignore = ""
+		flagUseGitignore = false
+		flagIgnorePatterns = []string{}
+
+		if registry == nil {
+			registry = processor.NewRegistry()
+			registry.Register(processor.NewMarkdownProcessor())
+		}
+
+		err := run(rootCmd, []string{})
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+
+		if _, err := os.Stat(flagOut); os.IsNotExist(err) {
+			t.Error("output file was not created")
+		}
+
+		data, err := os.ReadFile(flagOut)
+		if err != nil {
+			t.Fatalf("failed to read output: %v", err)
+		}
+
+		output := string(data)
+		if !strings.Contains(output, "main.go") {
+			t.Error("output should contain main.go")
+		}
+		if !strings.Contains(output, "util.go") {
+			t.Error("output should contain util.go")
+		}
+		if strings.Contains(output, "README.md") {
+			t.Error("output should not contain README.md")
+		}
+	})
+
+	t.Run("binary files", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(tmpDir, "logo.go"), []byte("\x89PNG\r\n\x1a\n\x00\x00"), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+		defer os.Remove(filepath.Join(tmpDir, "logo.go"))
+
+		flagDir = tmpDir
+		flagExts = []string{".go"}
+		flagOut = filepath.Join(tmpDir, "binary.md")
+		flagFormat = "markdown"
+		flagUseGitignore = false
+		defer func() { flagBinary = processor.BinarySkip }()
+
+		for policy, want := range map[string]string{
+			processor.BinarySkip:        "",
+			processor.BinaryPlaceholder: "[binary file: 10 bytes, image/png]",
+			processor.BinaryBase64:      "iVBORw0KGgoAAA==",
+		} {
+			flagBinary = policy
+			if err := run(rootCmd, []string{}); err != nil {
+				t.Fatalf("%s: run failed: %v", policy, err)
+			}
+			data, err := os.ReadFile(flagOut)
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+			output := string(data)
+			if !strings.Contains(output, "main.go") {
+				t.Errorf("%s: output should contain main.go", policy)
+			}
+			if got := strings.Contains(output, "logo.go"); got != (want != "") {
+				t.Errorf("%s: expected logo.go in output to be %t", policy, want != "")
+			}
+			if want != "" && !strings.Contains(output, want) {
+				t.Errorf("%s: output should contain %q:\n%s", policy, want, output)
+			}
+		}
+
+		flagBinary = "bogus"
+		if err := run(rootCmd, []string{}); err == nil {
+			t.Error("expected error for unknown --binary policy")
+		}
+	})
+
+	t.Run("size limits", func(t *testing.T) {
+		big := filepath.Join(tmpDir, "big.go")
+		if err := os.WriteFile(big, []byte(strings.Repeat("x", 2048)), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+		defer os.Remove(big)
+
+		flagDir = tmpDir
+		flagExts = []string{".go"}
+		flagOut = filepath.Join(tmpDir, "sized.md")
+		flagFormat = "markdown"
+		flagUseGitignore = false
+		flagMaxFileSize = "1K"
+		defer func() { flagMaxFileSize, flagOversize = "", processor.OversizeSkip }()
+
+		for mode, want := range map[string]string{
+			processor.OversizeSkip:     "",
+			processor.OversizeTruncate: "[truncated 1024 bytes]",
+		} {
+			flagOversize = mode
+			if err := run(rootCmd, []string{}); err != nil {
+				t.Fatalf("%s: run failed: %v", mode, err)
+			}
+			data, err := os.ReadFile(flagOut)
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+			output := string(data)
+			if !strings.Contains(output, "main.go") {
+				t.Errorf("%s: output should contain main.go", mode)
+			}
+			if got := strings.Contains(output, "big.go"); got != (want != "") {
+				t.Errorf("%s: expected big.go in output to be %t", mode, want != "")
+			}
+			if want != "" && !strings.Contains(output, want) {
+				t.Errorf("%s: output should contain %q", mode, want)
+			}
+		}
+
+		flagOversize = "bogus"
+		if err := run(rootCmd, []string{}); err == nil {
+			t.Error("expected error for unknown --oversize mode")
+		}
+	})
+
+	t.Run("include globs", func(t *testing.T) {
+		flagDir = tmpDir
+		flagOut = filepath.Join(tmpDir, "included.md")
+		flagFormat = "markdown"
+		flagUseGitignore = false
+		defer func() { flagExts, flagIncludes = nil, nil }()
+
+		tests := []struct {
+			exts     []string
+			includes []string
+			want     []string
+			wantErr  bool
+		}{
+			{includes: []string{"*.go", "README.md"}, want: []string{"main.go", "util.go", "README.md"}},
+			{exts: []string{".go"}, includes: []string{"main.*"}, want: []string{"main.go"}},
+			{wantErr: true},
+		}
+
+		for _, tt := range tests {
+			flagExts, flagIncludes = tt.exts, tt.includes
+			err := run(rootCmd, []string{})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error without --ext or --include")
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+
+			data, err := os.ReadFile(flagOut)
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+			var got []string
+			for _, name := range []string{"README.md", "main.go", "test.txt", "util.go"} {
+				if strings.Contains(string(data), "# "+name+"\n") {
+					got = append(got, name)
+				}
+			}
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("--ext %v --include %v: expected %v, got %v", tt.exts, tt.includes, want, got)
+			}
+		}
+	})
+
+	t.Run("directory tree", func(t *testing.T) {
+		blob := filepath.Join(tmpDir, "blob.go")
+		if err := os.WriteFile(blob, []byte("\x00\x01\x02"), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+		defer os.Remove(blob)
+
+		flagDir = tmpDir
+		flagExts = []string{".go"}
+		flagOut = filepath.Join(tmpDir, "tree.md")
+		flagFormat = "markdown"
+		flagUseGitignore = false
+		flagTree, flagTreeAnnotate = true, processor.TreeLines
+		defer func() { flagTree, flagTreeAnnotate = false, processor.TreePlain }()
+
+		if err := run(rootCmd, []string{}); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		data, err := os.ReadFile(flagOut)
+		if err != nil {
+			t.Fatalf("failed to read output: %v", err)
+		}
+		// blob.go is skipped as binary, so the tree leaves it out.
+		want := "# Directory tree\n\n<!-- amalgo:tree -->\n```text\n.\n├── main.go (1 line)\n└── util.go (1 line)\n```\n"
+		if !strings.HasPrefix(string(data), want) {
+			t.Errorf("expected output to start with %q, got:\n%s", want, data)
+		}
+
+		flagTreeAnnotate = "bogus"
+		if err := run(rootCmd, []string{}); err == nil {
+			t.Error("expected error for unknown --tree-annotate")
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		flagDir = tmpDir
+		flagExts = []string{".go"}
+		flagUseGitignore = false
+		flagTokenizer = "heuristic"
+		flagStatsFormat, flagStatsTop = statsJSON, 1
+		defer func() { flagTokenizer, flagStatsFormat, flagStatsTop = tokenizer.DefaultName, statsTable, 10 }()
+
+		oldStdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+		err := runStats(statsCmd, nil)
+		os.Stdout = oldStdout
+		w.Close()
+		if err != nil {
+			t.Fatalf("stats failed: %v", err)
+		}
+
+		var report processor.StatsReport
+		if err := json.NewDecoder(r).Decode(&report); err != nil {
+			t.Fatalf("failed to decode report: %v", err)
+		}
+		want := processor.StatsRow{Name: "total", Files: 2, Bytes: 26, Lines: 2, Tokens: 8}
+		if report.Total != want {
+			t.Errorf("expected total %+v, got %+v", want, report.Total)
+		}
+		if len(report.Largest) != 1 || len(report.Languages) != 1 || report.Languages[0].Name != "go" {
+			t.Errorf("unexpected report: %+v", report)
+		}
+
+		flagStatsFormat = "csv"
+		if err := runStats(statsCmd, nil); err == nil {
+			t.Error("expected error for unknown stats format")
+		}
+	})
+
+	t.Run("stats under a token budget", func(t *testing.T) {
+		big := filepath.Join(tmpDir, "big.go")
+		if err := os.WriteFile(big, []byte(strings.Repeat("// filler filler\n", 300)), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+		defer os.Remove(big)
+
+		flagDir = tmpDir
+		flagExts = []string{".go"}
+		flagOut = filepath.Join(tmpDir, "budget.md")
+		flagFormat = "markdown"
+		flagUseGitignore = false
+		flagTokenizer = "heuristic"
+		flagMaxTokens = 300
+		flagStats, flagStatsFormat = true, statsJSON
+		defer func() {
+			flagTokenizer, flagMaxTokens = tokenizer.DefaultName, 0
+			flagStats, flagStatsFormat = false, statsTable
+		}()
+
+		oldStderr := os.Stderr
+		r, w, _ := os.Pipe()
+		os.Stderr = w
+		err := run(rootCmd, []string{})
+		os.Stderr = oldStderr
+		w.Close()
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+
+		out, _ := io.ReadAll(r)
+		var report processor.StatsReport
+		if err := json.Unmarshal(out[bytes.IndexByte(out, '{'):], &report); err != nil {
+			t.Fatalf("failed to decode report: %v\n%s", err, out)
+		}
+		want := processor.StatsRow{Name: "total", Files: 2, Bytes: 26, Lines: 2, Tokens: 8}
+		if report.Total != want {
+			t.Errorf("expected only the files written to be counted, %+v, got %+v", want, report.Total)
+		}
+	})
+
+	t.Run("no matching files", func(t *testing.T) {
+		flagDir = tmpDir
+		flagExts = []string{".nonexistent"}
+		flagOut = filepath.Join(tmpDir, "empty.md")
+		flagIgnoreDirs = []string{}
+		flagFormat = "markdown"
+		flagGitignore = ""
+		flagUseGitignore = false
+		flagIgnorePatterns = []string{}
+
+		err := run(rootCmd, []string{})
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		flagDir = tmpDir
+		flagExts = []string{".go"}
+		flagFormat = "nonexistent"
+
+		err := run(rootCmd, []string{})
+		if err == nil {
+			t.Error("expected error for invalid format")
+		}
+	})
+
+	t.Run("flags the format does not support", func(t *testing.T) {
+		flagDir = tmpDir
+		flagExts = []string{".go"}
+
+		tests := []struct {
+			format string
+			flag   *bool
+		}{
+			{"diff", &flagTree},
+			{"diff", &flagTOC},
+			{"diff", &flagMeta},
+			{"json", &flagTOC},
+			{"markdown", &flagDiffFull},
+		}
+		for _, tt := range tests {
+			flagFormat = tt.format
+			*tt.flag = true
+			err := run(rootCmd, []string{})
+			*tt.flag = false
+			if err == nil || !strings.Contains(err.Error(), "is not supported with --format "+tt.format) {
+				t.Errorf("%s: expected unsupported flag error, got %v", tt.format, err)
+			}
+		}
+		flagFormat = "markdown"
+	})
+
+	t.Run("invalid extension", func(t *testing.T) {
+		flagDir = tmpDir
+		flagExts = []string{" , "}
+		flagFormat = "markdown"
+		defer func() { flagExts = []string{".go"} }()
+
+		if err := run(rootCmd, []string{}); err == nil {
+			t.Error("expected error for an --ext with no extensions in it")
+		}
+	})
+}
+
+func TestExecute(t *testing.T) {
+	t.Run("command is initialized", func(t *testing.T) {
+		if rootCmd == nil {
+			t.Error("rootCmd should be initialized")
+		}
+
+		if rootCmd.Use != "amalgo" {
+			t.Errorf("expected Use='amalgo', got '%s'", rootCmd.Use)
+		}
+	})
+}
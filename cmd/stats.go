@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"amalgo/processor"
+	"amalgo/tokenizer"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	statsTable = "table"
+	statsJSON  = "json"
+)
+
+var statsFormats = []string{statsTable, statsJSON}
+
+var (
+	flagStats       bool
+	flagStatsFormat string
+	flagStatsTop    int
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Report what a bundle of the selected files would hold.",
+	Long: `Stats selects files with the same flags and filters as the root command
+and reports their count, bytes, lines and estimated tokens per extension,
+top-level directory and language, along with the largest files. Binary
+files and size limits are applied first, so the numbers describe exactly
+what would be written.`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	addSelectionFlags(statsCmd)
+	addTokenizerFlags(statsCmd)
+	statsCmd.Flags().StringVarP(&flagStatsFormat, "format", "f", statsTable, fmt.Sprintf("Report format: %s", strings.Join(statsFormats, ", ")))
+	statsCmd.Flags().IntVarP(&flagStatsTop, "top", "n", 10, "Number of largest files to list (0 = all)")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if err := checkStatsFormat(); err != nil {
+		return err
+	}
+	enc, err := loadTokenizer(flagTokenizer, flagTokenVocab)
+	if err != nil {
+		return err
+	}
+
+	sel, err := selectFiles()
+	if err != nil {
+		return err
+	}
+	defer sel.close()
+
+	stats := processor.NewStats(enc)
+	for file := range sel.stage.apply(processor.LoadFilesSeq(sel.src.fsys, sel.files, sel.baseDir, flagJobs, false)) {
+		stats.Add(file)
+	}
+	if sel.stage.err != nil {
+		return sel.stage.err
+	}
+	printSummary("", sel.stage.skipped)
+
+	return printStats(os.Stdout, stats.Report(flagStatsTop), flagStatsFormat)
+}
+
+func checkStatsFormat() error {
+	if !slices.Contains(statsFormats, flagStatsFormat) {
+		return fmt.Errorf("invalid stats format %q (expected one of %s)", flagStatsFormat, strings.Join(statsFormats, ", "))
+	}
+	return nil
+}
+
+// countFiles passes files through unchanged, adding each to stats on the
+// way.
+func countFiles(files iter.Seq[processor.FileInfo], stats *processor.Stats) iter.Seq[processor.FileInfo] {
+	return func(yield func(processor.FileInfo) bool) {
+		for f := range files {
+			stats.Add(f)
+			if !yield(f) {
+				return
+			}
+		}
+	}
+}
+
+// printFileStats reports on files, as they were written, to stderr.
+func printFileStats(files []processor.FileInfo, enc tokenizer.Encoder) error {
+	stats := processor.NewStats(enc)
+	for _, f := range files {
+		stats.Add(f)
+	}
+	return printStats(os.Stderr, stats.Report(flagStatsTop), flagStatsFormat)
+}
+
+func printStats(w io.Writer, report processor.StatsReport, format string) error {
+	if format == statsJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	t := report.Total
+	fmt.Fprintf(w, "%d file(s), %d bytes, %d lines, %d tokens (%s)\n", t.Files, t.Bytes, t.Lines, t.Tokens, report.Encoder)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, section := range []struct {
+		title string
+		rows  []processor.StatsRow
+	}{
+		{"EXTENSION", report.Extensions},
+		{"DIRECTORY", report.Directories},
+		{"LANGUAGE", report.Languages},
+	} {
+		fmt.Fprintf(tw, "\n%s\tFILES\tBYTES\tLINES\tTOKENS\n", section.title)
+		for _, r := range section.rows {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", r.Name, r.Files, r.Bytes, r.Lines, r.Tokens)
+		}
+	}
+
+	fmt.Fprint(tw, "\nLARGEST FILES\tBYTES\tLINES\tTOKENS\n")
+	for _, f := range report.Largest {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", f.Path, f.Bytes, f.Lines, f.Tokens)
+	}
+	return tw.Flush()
+}
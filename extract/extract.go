@@ -0,0 +1,115 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"amalgo/processor"
+)
+
+type Options struct {
+	TargetDir string
+	DryRun    bool
+	Overwrite bool
+}
+
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionOverwrite Action = "overwrite"
+)
+
+type Result struct {
+	RelPath string
+	Path    string
+	Action  Action
+	Size    int
+}
+
+var ErrUnsafePath = errors.New("path escapes target directory")
+
+// Write places each file under opts.TargetDir. Every path is validated
+// before anything is written, so a bundle with one bad entry leaves the
+// target untouched.
+func Write(files []processor.FileInfo, opts Options) ([]Result, error) {
+	results := make([]Result, 0, len(files))
+	seen := make(map[string]struct{}, len(files))
+
+	for _, file := range files {
+		dest, err := SafeJoin(opts.TargetDir, file.RelPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, dup := seen[dest]; dup {
+			return nil, fmt.Errorf("duplicate path in bundle: %s", file.RelPath)
+		}
+		seen[dest] = struct{}{}
+
+		action := ActionCreate
+		info, err := os.Lstat(dest)
+		switch {
+		case err == nil && info.IsDir():
+			return nil, fmt.Errorf("%s exists and is a directory", dest)
+		case err == nil && !opts.Overwrite:
+			return nil, fmt.Errorf("%s already exists (use --overwrite to replace it)", dest)
+		case err == nil:
+			action = ActionOverwrite
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+
+		results = append(results, Result{
+			RelPath: file.RelPath,
+			Path:    dest,
+			Action:  action,
+			Size:    len(file.Content),
+		})
+	}
+
+	if opts.DryRun {
+		return results, nil
+	}
+
+	for i, file := range files {
+		dest := results[i].Path
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return results[:i], fmt.Errorf("creating directory for %s: %w", file.RelPath, err)
+		}
+		if err := os.WriteFile(dest, file.Content, 0o644); err != nil {
+			return results[:i], fmt.Errorf("writing %s: %w", file.RelPath, err)
+		}
+	}
+
+	return results, nil
+}
+
+func SafeJoin(targetDir, relPath string) (string, error) {
+	if relPath == "" {
+		return "", fmt.Errorf("%w: empty path", ErrUnsafePath)
+	}
+
+	local := filepath.FromSlash(relPath)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, relPath)
+	}
+
+	// A symlink inside the target could still redirect the write elsewhere.
+	dir := targetDir
+	for _, part := range strings.Split(local, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if err != nil {
+			break
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %s traverses symlink %s", ErrUnsafePath, relPath, dir)
+		}
+	}
+
+	return filepath.Join(targetDir, local), nil
+}
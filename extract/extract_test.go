@@ -0,0 +1,223 @@
+package extract
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"amalgo/processor"
+)
+
+func TestSafeJoin(t *testing.T) {
+	tests := []struct {
+		name    string
+		rel     string
+		wantErr bool
+	}{
+		{"simple file", "main.go", false},
+		{"nested file", "cmd/root.go", false},
+		{"dot segments inside target", "cmd/../main.go", false},
+		{"empty path", "", true},
+		{"parent escape", "../outside.go", true},
+		{"nested parent escape", "cmd/../../outside.go", true},
+		{"absolute path", "/etc/passwd", true},
+	}
+
+	target := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest, err := SafeJoin(target, tt.rel)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsafePath) {
+					t.Errorf("expected ErrUnsafePath, got %v (dest %s)", err, dest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rel, err := filepath.Rel(target, dest); err != nil || !filepath.IsLocal(rel) {
+				t.Errorf("destination %s is outside target %s", dest, target)
+			}
+		})
+	}
+
+	t.Run("symlink inside target", func(t *testing.T) {
+		outside := t.TempDir()
+		if err := os.Symlink(outside, filepath.Join(target, "link")); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+
+		if _, err := SafeJoin(target, "link/file.go"); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("expected ErrUnsafePath, got %v", err)
+		}
+	})
+}
+
+func TestWrite(t *testing.T) {
+	files := []processor.FileInfo{
+		{RelPath: "main.go", Content: []byte("package main\n")},
+		{RelPath: filepath.Join("cmd", "root.go"), Content: []byte("package cmd\n")},
+	}
+
+	t.Run("writes files", func(t *testing.T) {
+		target := t.TempDir()
+
+		results, err := Write(files, Options{TargetDir: target})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(results))
+		}
+
+		for _, f := range files {
+			data, err := os.ReadFile(filepath.Join(target, f.RelPath))
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", f.RelPath, err)
+			}
+			if string(data) != string(f.Content) {
+				t.Errorf("%s: content mismatch", f.RelPath)
+			}
+		}
+	})
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		target := t.TempDir()
+
+		results, err := Write(files, Options{TargetDir: target, DryRun: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 2 || results[0].Action != ActionCreate {
+			t.Errorf("unexpected results: %+v", results)
+		}
+
+		entries, _ := os.ReadDir(target)
+		if len(entries) != 0 {
+			t.Errorf("expected empty target, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("refuses to overwrite by default", func(t *testing.T) {
+		target := t.TempDir()
+		existing := filepath.Join(target, "main.go")
+		if err := os.WriteFile(existing, []byte("original"), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+
+		if _, err := Write(files, Options{TargetDir: target}); err == nil {
+			t.Fatal("expected error for existing file")
+		}
+
+		data, _ := os.ReadFile(existing)
+		if string(data) != "original" {
+			t.Error("existing file should be untouched")
+		}
+		if _, err := os.Stat(filepath.Join(target, "cmd")); !os.IsNotExist(err) {
+			t.Error("no files should be written when validation fails")
+		}
+	})
+
+	t.Run("overwrite replaces existing files", func(t *testing.T) {
+		target := t.TempDir()
+		existing := filepath.Join(target, "main.go")
+		if err := os.WriteFile(existing, []byte("original"), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+
+		results, err := Write(files, Options{TargetDir: target, Overwrite: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if results[0].Action != ActionOverwrite || results[1].Action != ActionCreate {
+			t.Errorf("unexpected actions: %+v", results)
+		}
+
+		data, _ := os.ReadFile(existing)
+		if string(data) != "package main\n" {
+			t.Errorf("expected file to be replaced, got %q", data)
+		}
+	})
+
+	t.Run("rejects escaping paths", func(t *testing.T) {
+		target := t.TempDir()
+		bad := []processor.FileInfo{
+			{RelPath: "ok.go", Content: []byte("ok")},
+			{RelPath: "../evil.go", Content: []byte("evil")},
+		}
+
+		if _, err := Write(bad, Options{TargetDir: target}); !errors.Is(err, ErrUnsafePath) {
+			t.Fatalf("expected ErrUnsafePath, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(target, "ok.go")); !os.IsNotExist(err) {
+			t.Error("no files should be written when a path is unsafe")
+		}
+	})
+
+	t.Run("rejects duplicate paths", func(t *testing.T) {
+		dup := []processor.FileInfo{
+			{RelPath: "a.go", Content: []byte("1")},
+			{RelPath: "a.go", Content: []byte("2")},
+		}
+
+		if _, err := Write(dup, Options{TargetDir: t.TempDir()}); err == nil {
+			t.Error("expected error for duplicate paths")
+		}
+	})
+}
+
+func TestRoundTrip_Markdown(t *testing.T) {
+	source := t.TempDir()
+	tree := map[string]string{
+		"main.go":          "package main\n\nfunc main() {}\n",
+		"cmd/root.go":      "package cmd\n\nvar s = \"```\"\n",
+		"docs/guide.md":    "# Guide\n\n````\nnested\n````\n",
+		"scripts/build.sh": "#!/bin/sh\r\necho build\r\n",
+		"empty.txt":        "",
+	}
+
+	var paths []string
+	for rel, content := range tree {
+		path := filepath.Join(source, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	infos, err := processor.LoadFiles(paths, source)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	proc := processor.NewMarkdownProcessor()
+	bundle, err := proc.Process(infos, processor.Options{HeadingLevel: 1})
+	if err != nil {
+		t.Fatalf("process failed: %v", err)
+	}
+
+	extracted, err := proc.Extract(bundle)
+	if err != nil {
+		t.Fatalf("extract failed: %v", err)
+	}
+
+	target := t.TempDir()
+	if _, err := Write(extracted, Options{TargetDir: target}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	for rel, content := range tree {
+		data, err := os.ReadFile(filepath.Join(target, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatalf("missing %s: %v", rel, err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: expected %q, got %q", rel, content, data)
+		}
+	}
+}
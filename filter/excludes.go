@@ -0,0 +1,228 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// LoadGitExcludes adds the user's global excludes file and the repository's
+// info/exclude file to a nested filter. Like git, both rank below every
+// .gitignore file. It does nothing when baseDir is not inside a work tree.
+func (g *GitignoreFilter) LoadGitExcludes() error {
+	root := FindRepoRoot(g.baseDir)
+	if root == "" {
+		return nil
+	}
+	gitDir := GitDir(root)
+
+	var excludes []gitignore.Pattern
+	for _, path := range []string{globalExcludesFile(gitDir), filepath.Join(commonDir(gitDir), "info", "exclude")} {
+		if path == "" {
+			continue
+		}
+		patterns, err := readPatterns(path, nil)
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", path, err)
+		}
+		excludes = append(excludes, patterns...)
+	}
+
+	g.patterns = append(excludes, g.patterns...)
+	return nil
+}
+
+// GitDir returns the git directory of the work tree at root, following the
+// "gitdir:" indirection used by linked worktrees and submodules.
+func GitDir(root string) string {
+	dotGit := filepath.Join(root, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil || info.IsDir() {
+		return dotGit
+	}
+
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return dotGit
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	dir, ok := strings.CutPrefix(strings.TrimSpace(line), "gitdir:")
+	if !ok {
+		return dotGit
+	}
+	dir = strings.TrimSpace(dir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir
+}
+
+// commonDir returns the directory shared by all worktrees of a repository,
+// which is where info/exclude lives.
+func commonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	dir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return dir
+}
+
+// globalExcludesFile resolves core.excludesFile the way git does: system,
+// XDG, user and repository config in increasing precedence, falling back to
+// $XDG_CONFIG_HOME/git/ignore when no config sets it. go-git's
+// gitignore.LoadGlobalPatterns and LoadSystemPatterns are not used because
+// each reads only ~/.gitconfig or /etc/gitconfig, without the XDG files,
+// the repository config or includes, and would miss where most setups keep
+// their excludes.
+func globalExcludesFile(gitDir string) string {
+	home, _ := os.UserHomeDir()
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" && home != "" {
+		xdg = filepath.Join(home, ".config")
+	}
+
+	configs := []string{"/etc/gitconfig"}
+	if xdg != "" {
+		configs = append(configs, filepath.Join(xdg, "git", "config"))
+	}
+	if home != "" {
+		configs = append(configs, filepath.Join(home, ".gitconfig"))
+	}
+	configs = append(configs, filepath.Join(gitDir, "config"))
+
+	var excludesFile string
+	for _, path := range configs {
+		if value := readExcludesFile(path, gitDir, 0); value != "" {
+			excludesFile = value
+		}
+	}
+
+	switch {
+	case excludesFile == "":
+		if xdg == "" {
+			return ""
+		}
+		return filepath.Join(xdg, "git", "ignore")
+	case home == "" && (excludesFile == "~" || strings.HasPrefix(excludesFile, "~/")):
+		return ""
+	}
+	return expandHome(excludesFile, home)
+}
+
+// maxIncludeDepth bounds nested includes, as in git, so that a config
+// including itself does not recurse forever.
+const maxIncludeDepth = 10
+
+// readExcludesFile returns the last core.excludesFile set in the config file
+// at path, following [include] and [includeIf "gitdir:..."] sections at the
+// position where each first appears. Other includeIf conditions are never
+// met, as they depend on state this package does not read.
+func readExcludesFile(path, gitDir string, depth int) string {
+	if depth > maxIncludeDepth {
+		return ""
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	cfg := config.New()
+	if err := config.NewDecoder(bufio.NewReader(file)).Decode(cfg); err != nil {
+		return ""
+	}
+
+	home, _ := os.UserHomeDir()
+	var value string
+	for _, section := range cfg.Sections {
+		var includes []string
+		switch {
+		case section.IsName("core"):
+			if v := section.Option("excludesfile"); v != "" {
+				value = v
+			}
+		case section.IsName("include"):
+			includes = section.OptionAll("path")
+		case section.IsName("includeIf"):
+			for _, sub := range section.Subsections {
+				if gitdirMatches(sub.Name, path, gitDir, home) {
+					includes = append(includes, sub.OptionAll("path")...)
+				}
+			}
+		}
+
+		for _, include := range includes {
+			include = expandHome(include, home)
+			if !filepath.IsAbs(include) {
+				include = filepath.Join(filepath.Dir(path), include)
+			}
+			if v := readExcludesFile(include, gitDir, depth+1); v != "" {
+				value = v
+			}
+		}
+	}
+	return value
+}
+
+// gitdirMatches reports whether an includeIf condition of the form
+// "gitdir:pattern" or "gitdir/i:pattern" holds for gitDir. As in git, a
+// pattern may start with "~/" or, relative to the config file, "./"; other
+// relative patterns match at any depth, and a trailing slash matches
+// everything below.
+func gitdirMatches(condition, configPath, gitDir, home string) bool {
+	pattern, ok := strings.CutPrefix(condition, "gitdir:")
+	fold := false
+	if !ok {
+		if pattern, ok = strings.CutPrefix(condition, "gitdir/i:"); !ok {
+			return false
+		}
+		fold = true
+	}
+
+	below := strings.HasSuffix(pattern, "/")
+	switch {
+	case strings.HasPrefix(pattern, "./"):
+		pattern = filepath.Join(filepath.Dir(configPath), pattern)
+	case pattern == "~" || strings.HasPrefix(pattern, "~/"):
+		if home == "" {
+			return false
+		}
+		pattern = expandHome(pattern, home)
+	}
+	pattern = filepath.ToSlash(pattern)
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	pattern = path.Clean(pattern)
+	if below {
+		pattern += "/**"
+	}
+
+	dir, err := filepath.Abs(gitDir)
+	if err != nil {
+		return false
+	}
+	dir = filepath.ToSlash(dir)
+	if fold {
+		pattern, dir = strings.ToLower(pattern), strings.ToLower(dir)
+	}
+	return matchGlob(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(strings.TrimPrefix(dir, "/"), "/"))
+}
+
+// expandHome replaces a leading "~" in p with home.
+func expandHome(p, home string) string {
+	if home != "" && (p == "~" || strings.HasPrefix(p, "~/")) {
+		return filepath.Join(home, p[1:])
+	}
+	return p
+}
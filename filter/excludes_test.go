@@ -0,0 +1,183 @@
+package filter
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadGitExcludes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	repo := t.TempDir()
+	writeTree(t, repo, map[string]string{
+		".git/HEAD":         "ref: refs/heads/main\n",
+		".git/info/exclude": "*.local\nscratch/\n",
+		".gitignore":        "!keep.local\n",
+	})
+	writeTree(t, home, map[string]string{
+		".config/git/ignore": "*.swp\n*.local\n",
+	})
+
+	filter, err := NewNestedGitignoreFilter(repo, "", nil)
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+	if err := filter.LoadGitExcludes(); err != nil {
+		t.Fatalf("failed to load excludes: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"notes.local", false, false},
+		{"scratch", true, false},
+		{"main.go.swp", false, false},
+		{"keep.local", false, true},
+		{"main.go", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path := filepath.Join(repo, tt.path)
+			mockEntry := &mockDirEntry{name: tt.path, isDir: tt.isDir}
+
+			if result := filter.ShouldInclude(path, mockEntry); result != tt.expected {
+				t.Errorf("expected %v, got %v for path %s", tt.expected, result, tt.path)
+			}
+		})
+	}
+
+	t.Run("outside a repository", func(t *testing.T) {
+		dir := t.TempDir()
+		filter, err := NewNestedGitignoreFilter(dir, "", nil)
+		if err != nil {
+			t.Fatalf("failed to create filter: %v", err)
+		}
+		if err := filter.LoadGitExcludes(); err != nil {
+			t.Fatalf("failed to load excludes: %v", err)
+		}
+		if !filter.ShouldInclude(filepath.Join(dir, "a.swp"), &mockDirEntry{name: "a.swp"}) {
+			t.Error("global excludes should not apply outside a repository")
+		}
+	})
+}
+
+func TestGlobalExcludesFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	gitDir := filepath.Join(t.TempDir(), ".git")
+
+	if got, want := globalExcludesFile(gitDir), filepath.Join(home, ".config", "git", "ignore"); got != want {
+		t.Errorf("expected XDG default %s, got %s", want, got)
+	}
+
+	writeTree(t, home, map[string]string{
+		".gitconfig": "[core]\n\texcludesFile = ~/.gitignore_global\n",
+	})
+	if got, want := globalExcludesFile(gitDir), filepath.Join(home, ".gitignore_global"); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	writeTree(t, gitDir, map[string]string{
+		"config": "[core]\n\texcludesfile = /repo/excludes\n",
+	})
+	if got := globalExcludesFile(gitDir); got != "/repo/excludes" {
+		t.Errorf("expected repository config to win, got %s", got)
+	}
+}
+
+func TestGlobalExcludesFile_XDG(t *testing.T) {
+	home := t.TempDir()
+	xdg := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	gitDir := filepath.Join(t.TempDir(), ".git")
+
+	if got, want := globalExcludesFile(gitDir), filepath.Join(xdg, "git", "ignore"); got != want {
+		t.Errorf("expected XDG default %s, got %s", want, got)
+	}
+
+	writeTree(t, xdg, map[string]string{
+		"git/config": "[core]\n\texcludesFile = ~/xdg-excludes\n",
+	})
+	if got, want := globalExcludesFile(gitDir), filepath.Join(home, "xdg-excludes"); got != want {
+		t.Errorf("expected XDG config with ~ expanded, %s, got %s", want, got)
+	}
+
+	writeTree(t, home, map[string]string{
+		".gitconfig": "[core]\n\texcludesFile = ~\n",
+	})
+	if got := globalExcludesFile(gitDir); got != home {
+		t.Errorf("expected ~/.gitconfig to win over the XDG config with %s, got %s", home, got)
+	}
+}
+
+func TestGlobalExcludesFile_Includes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	work := filepath.Join(home, "Work", "app", ".git")
+	other := filepath.Join(home, "other", ".git")
+
+	tests := []struct {
+		name     string
+		config   string
+		gitDir   string
+		expected string
+	}{
+		{"include", "[include]\n\tpath = extra\n", other, "/from/extra"},
+		{"include before core", "[include]\n\tpath = extra\n[core]\n\texcludesFile = /from/main\n", other, "/from/main"},
+		{"include after core", "[core]\n\texcludesFile = /from/main\n[include]\n\tpath = extra\n", other, "/from/extra"},
+		{"include with ~", "[include]\n\tpath = ~/extra\n", other, "/from/extra"},
+		{"include cycle", "[include]\n\tpath = .gitconfig\n[core]\n\texcludesFile = /from/main\n", other, "/from/main"},
+		{"includeIf gitdir under", "[includeIf \"gitdir:~/Work/\"]\n\tpath = extra\n", work, "/from/extra"},
+		{"includeIf gitdir elsewhere", "[includeIf \"gitdir:~/Work/\"]\n\tpath = extra\n", other, ""},
+		{"includeIf gitdir at any depth", "[includeIf \"gitdir:app/.git\"]\n\tpath = extra\n", work, "/from/extra"},
+		{"includeIf gitdir relative to config", "[includeIf \"gitdir:./Work/\"]\n\tpath = extra\n", work, "/from/extra"},
+		{"includeIf gitdir case", "[includeIf \"gitdir:~/work/\"]\n\tpath = extra\n", work, ""},
+		{"includeIf gitdir/i", "[includeIf \"gitdir/i:~/work/\"]\n\tpath = extra\n", work, "/from/extra"},
+		{"includeIf onbranch", "[includeIf \"onbranch:main\"]\n\tpath = extra\n", work, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTree(t, home, map[string]string{
+				".gitconfig": tt.config,
+				"extra":      "[core]\n\texcludesFile = /from/extra\n",
+			})
+
+			expected := tt.expected
+			if expected == "" {
+				expected = filepath.Join(home, ".config", "git", "ignore")
+			}
+			if got := globalExcludesFile(tt.gitDir); got != expected {
+				t.Errorf("expected %s, got %s", expected, got)
+			}
+		})
+	}
+}
+
+func TestGitDir(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main/.git/HEAD":                   "ref: refs/heads/main\n",
+		"main/.git/worktrees/wt/commondir": "../..\n",
+		"wt/.git":                          "gitdir: ../main/.git/worktrees/wt\n",
+	})
+
+	gitDir := GitDir(filepath.Join(root, "wt"))
+	if want := filepath.Join(root, "main", ".git", "worktrees", "wt"); gitDir != want {
+		t.Errorf("expected %s, got %s", want, gitDir)
+	}
+	if got, want := commonDir(gitDir), filepath.Join(root, "main", ".git"); got != want {
+		t.Errorf("expected common dir %s, got %s", want, got)
+	}
+}
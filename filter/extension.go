@@ -6,13 +6,28 @@ import (
 	"strings"
 )
 
+// ExtensionFilter includes files whose name ends in one of its extensions,
+// compared case-insensitively, or whose base name is exactly one of its
+// names, such as "Makefile" or "go.mod". Extensions may span several dots,
+// as in ".d.ts" or ".test.js".
 type ExtensionFilter struct {
 	extensions map[string]struct{}
+	names      map[string]struct{}
+	languages  map[string]struct{}
+	fsys       fs.FS
+	baseDir    string
 }
 
 func NewExtensionFilter(extensions map[string]struct{}) *ExtensionFilter {
+	return NewNameFilter(nil, extensions)
+}
+
+// NewNameFilter is NewExtensionFilter that also includes files whose base
+// name is one of names.
+func NewNameFilter(names, extensions map[string]struct{}) *ExtensionFilter {
 	return &ExtensionFilter{
 		extensions: extensions,
+		names:      names,
 	}
 }
 
@@ -21,7 +36,19 @@ func (e *ExtensionFilter) ShouldInclude(path string, d fs.DirEntry) bool {
 		return true
 	}
 
-	ext := strings.ToLower(filepath.Ext(path))
-	_, ok := e.extensions[ext]
-	return ok
+	base := filepath.Base(path)
+	if _, ok := e.names[base]; ok {
+		return true
+	}
+
+	lower := strings.ToLower(base)
+	for i := 0; i < len(lower); i++ {
+		if lower[i] != '.' {
+			continue
+		}
+		if _, ok := e.extensions[lower[i:]]; ok {
+			return true
+		}
+	}
+	return e.matchShebang(path, d)
 }
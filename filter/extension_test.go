@@ -93,6 +93,61 @@ func TestExtensionFilter(t *testing.T) {
 	}
 }
 
+func TestExtensionFilter_CompoundSuffixes(t *testing.T) {
+	filter := NewExtensionFilter(map[string]struct{}{".min.js": {}, ".d.ts": {}, ".gitignore": {}})
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/project/test.min.js", true},
+		{"/project/vendor.MIN.JS", true},
+		{"/project/test.js", false},
+		{"/project/types.d.ts", true},
+		{"/project/index.ts", false},
+		{"/project/.gitignore", true},
+		{"/project/min.js", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			mockEntry := &mockDirEntry{name: tt.path}
+			if result := filter.ShouldInclude(tt.path, mockEntry); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestNameFilter(t *testing.T) {
+	filter := NewNameFilter(
+		map[string]struct{}{"Makefile": {}, "go.mod": {}},
+		map[string]struct{}{".go": {}},
+	)
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/project/Makefile", true},
+		{"/project/sub/Makefile", true},
+		{"/project/makefile", false},
+		{"/project/Makefile.bak", false},
+		{"/project/go.mod", true},
+		{"/project/go.sum", false},
+		{"/project/main.go", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			mockEntry := &mockDirEntry{name: tt.path}
+			if result := filter.ShouldInclude(tt.path, mockEntry); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestExtensionFilter_EmptyExtensions(t *testing.T) {
 	filter := NewExtensionFilter(map[string]struct{}{})
 
@@ -2,7 +2,9 @@ package filter
 
 import (
 	"io/fs"
+	"os"
 	"path/filepath"
+	"strings"
 )
 
 type Filter interface {
@@ -31,12 +33,20 @@ func (c *Chain) ShouldInclude(path string, d fs.DirEntry) bool {
 }
 
 type Config struct {
-	Extensions     map[string]struct{}
-	IgnoreDirs     map[string]struct{}
-	IncludeHidden  bool
-	GitignorePath  string
-	CustomPatterns []string
-	BaseDir        string
+	Extensions      map[string]struct{}
+	Names           map[string]struct{}
+	Languages       map[string]struct{}
+	IgnoreDirs      map[string]struct{}
+	IncludeHidden   bool
+	GitignorePath   string
+	NestedGitignore bool
+	GitExcludes     bool
+	SourceFS        fs.FS
+	SourceSubdir    string
+	FilesFS         fs.FS
+	CustomPatterns  []string
+	IncludePatterns []string
+	BaseDir         string
 }
 
 func BuildChain(cfg Config) (*Chain, error) {
@@ -50,7 +60,24 @@ func BuildChain(cfg Config) (*Chain, error) {
 		chain.Add(NewDirFilter(cfg.IgnoreDirs))
 	}
 
-	if cfg.GitignorePath != "" || len(cfg.CustomPatterns) > 0 {
+	if cfg.NestedGitignore {
+		var gitFilter *GitignoreFilter
+		var err error
+		if cfg.SourceFS != nil {
+			gitFilter, err = NewFSGitignoreFilter(cfg.SourceFS, cfg.SourceSubdir, cfg.BaseDir, cfg.GitignorePath, cfg.CustomPatterns)
+		} else {
+			gitFilter, err = NewNestedGitignoreFilter(cfg.BaseDir, cfg.GitignorePath, cfg.CustomPatterns)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if cfg.GitExcludes {
+			if err := gitFilter.LoadGitExcludes(); err != nil {
+				return nil, err
+			}
+		}
+		chain.Add(gitFilter)
+	} else if cfg.GitignorePath != "" || len(cfg.CustomPatterns) > 0 {
 		gitFilter, err := NewGitignoreFilter(cfg.BaseDir, cfg.GitignorePath, cfg.CustomPatterns)
 		if err != nil {
 			return nil, err
@@ -58,13 +85,61 @@ func BuildChain(cfg Config) (*Chain, error) {
 		chain.Add(gitFilter)
 	}
 
-	if len(cfg.Extensions) > 0 {
-		chain.Add(NewExtensionFilter(cfg.Extensions))
+	if len(cfg.IncludePatterns) > 0 {
+		includeFilter, err := NewIncludeFilter(cfg.BaseDir, cfg.IncludePatterns)
+		if err != nil {
+			return nil, err
+		}
+		chain.Add(includeFilter)
+	}
+
+	if len(cfg.Extensions) > 0 || len(cfg.Names) > 0 || len(cfg.Languages) > 0 {
+		nameFilter := NewNameFilter(cfg.Names, cfg.Extensions)
+		if len(cfg.Languages) > 0 {
+			fsys := cfg.FilesFS
+			if fsys == nil {
+				fsys = os.DirFS(cfg.BaseDir)
+			}
+			nameFilter.MatchShebangs(fsys, cfg.BaseDir, cfg.Languages)
+		}
+		chain.Add(nameFilter)
 	}
 
 	return chain, nil
 }
 
+// IncludesPath reports whether a walk of baseDir filtered by f would reach
+// relPath, checking each parent directory before the file itself. It is
+// meant for paths that no longer exist on disk, such as deleted files.
+func IncludesPath(f Filter, baseDir, relPath string) bool {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	path := baseDir
+	for i, part := range parts {
+		path = filepath.Join(path, part)
+		if !f.ShouldInclude(path, pathEntry{name: part, dir: i < len(parts)-1}) {
+			return false
+		}
+	}
+	return true
+}
+
+type pathEntry struct {
+	name string
+	dir  bool
+}
+
+func (e pathEntry) Name() string { return e.name }
+func (e pathEntry) IsDir() bool  { return e.dir }
+
+func (e pathEntry) Type() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func (e pathEntry) Info() (fs.FileInfo, error) { return nil, fs.ErrNotExist }
+
 func RelPath(path, base string) string {
 	if rel, err := filepath.Rel(base, path); err == nil {
 		return rel
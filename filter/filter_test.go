@@ -126,6 +126,28 @@ func TestBuildChain(t *testing.T) {
 	})
 }
 
+func TestIncludesPath(t *testing.T) {
+	chain := NewChain(NewHiddenFilter(), NewExtensionFilter(map[string]struct{}{".go": {}}))
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"main.go", true},
+		{"pkg/util.go", true},
+		{"pkg/readme.md", false},
+		{".hidden/main.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if result := IncludesPath(chain, "/project", tt.path); result != tt.expected {
+				t.Errorf("expected %v, got %v for path %s", tt.expected, result, tt.path)
+			}
+		})
+	}
+}
+
 func TestRelPath(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -2,68 +2,191 @@ package filter
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 )
 
+// GitignoreFilter excludes paths matched by gitignore-style patterns.
+// Patterns are kept in increasing order of precedence and, like git, the
+// last matching pattern decides. In nested mode every .gitignore between
+// the repository root and a path is loaded on demand, scoped to its own
+// directory, so deeper files override shallower ones.
 type GitignoreFilter struct {
-	matcher gitignore.Matcher
-	baseDir string
+	baseDir  string
+	fsys     fs.FS
+	prefix   []string
+	patterns []gitignore.Pattern
+	custom   []gitignore.Pattern
+	nested   bool
+	loaded   map[string]struct{}
 }
 
 func NewGitignoreFilter(baseDir, gitignorePath string, customPatterns []string) (*GitignoreFilter, error) {
-	var patterns []gitignore.Pattern
+	g := &GitignoreFilter{
+		baseDir: baseDir,
+		custom:  parsePatterns(customPatterns),
+	}
 
 	if gitignorePath != "" {
 		filePatterns, err := loadGitignoreFile(gitignorePath, baseDir)
 		if err != nil {
 			return nil, fmt.Errorf("loading gitignore file: %w", err)
 		}
-		patterns = append(patterns, filePatterns...)
+		g.patterns = append(g.patterns, filePatterns...)
 	}
 
-	for _, pattern := range customPatterns {
-		pattern = strings.TrimSpace(pattern)
-		if pattern == "" || strings.HasPrefix(pattern, "#") {
-			continue
+	return g, nil
+}
+
+// NewNestedGitignoreFilter discovers .gitignore files during the scan. If
+// baseDir is inside a git work tree, the .gitignore files of its parent
+// directories up to the work tree root apply as well. An explicit
+// gitignorePath is applied with the lowest precedence, relative to baseDir.
+func NewNestedGitignoreFilter(baseDir, gitignorePath string, customPatterns []string) (*GitignoreFilter, error) {
+	root := baseDir
+	var prefix []string
+	if repoRoot := FindRepoRoot(baseDir); repoRoot != "" {
+		absBase, err := filepath.Abs(baseDir)
+		if err != nil {
+			return nil, err
+		}
+		if rel, err := filepath.Rel(repoRoot, absBase); err == nil && rel != "." {
+			root = repoRoot
+			prefix = strings.Split(filepath.ToSlash(rel), "/")
 		}
-		patterns = append(patterns, gitignore.ParsePattern(pattern, nil))
 	}
 
-	if len(patterns) == 0 {
-		return &GitignoreFilter{
-			matcher: nil,
-			baseDir: baseDir,
-		}, nil
-	}
+	return newNestedGitignoreFilter(os.DirFS(root), prefix, baseDir, gitignorePath, customPatterns)
+}
 
-	matcher := gitignore.NewMatcher(patterns)
+// NewFSGitignoreFilter is the nested filter for a tree that is not checked
+// out, such as a git revision. fsys holds the whole tree and baseDir stands
+// for its directory subdir ("." for the top).
+func NewFSGitignoreFilter(fsys fs.FS, subdir, baseDir, gitignorePath string, customPatterns []string) (*GitignoreFilter, error) {
+	var prefix []string
+	if subdir = path.Clean(subdir); subdir != "." {
+		prefix = strings.Split(subdir, "/")
+	}
+	return newNestedGitignoreFilter(fsys, prefix, baseDir, gitignorePath, customPatterns)
+}
 
-	return &GitignoreFilter{
-		matcher: matcher,
+func newNestedGitignoreFilter(fsys fs.FS, prefix []string, baseDir, gitignorePath string, customPatterns []string) (*GitignoreFilter, error) {
+	g := &GitignoreFilter{
 		baseDir: baseDir,
-	}, nil
+		fsys:    fsys,
+		prefix:  prefix,
+		custom:  parsePatterns(customPatterns),
+		nested:  true,
+		loaded:  make(map[string]struct{}),
+	}
+
+	if gitignorePath != "" {
+		filePatterns, err := readPatterns(gitignorePath, g.prefix)
+		if err != nil {
+			return nil, fmt.Errorf("loading gitignore file: %w", err)
+		}
+		g.patterns = append(g.patterns, filePatterns...)
+	}
+
+	for i := 0; i <= len(g.prefix); i++ {
+		if err := g.loadDir(g.prefix[:i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
 }
 
 func (g *GitignoreFilter) ShouldInclude(path string, d fs.DirEntry) bool {
-	if g.matcher == nil {
+	relPath := filepath.ToSlash(RelPath(path, g.baseDir))
+	if relPath == "." {
 		return true
 	}
-	relPath := RelPath(path, g.baseDir)
 
-	relPath = filepath.ToSlash(relPath)
+	parts := append(append([]string{}, g.prefix...), strings.Split(relPath, "/")...)
 
-	parts := strings.Split(relPath, "/")
+	if g.nested {
+		for i := len(g.prefix) + 1; i < len(parts); i++ {
+			if err := g.loadDir(parts[:i]); err != nil {
+				fmt.Fprintf(os.Stderr, "warn: %v\n", err)
+			}
+		}
+	}
 
-	return !g.matcher.Match(parts, d.IsDir())
+	isDir := d.IsDir()
+	if excluded, ok := match(g.custom, parts, isDir); ok {
+		return !excluded
+	}
+	if excluded, ok := match(g.patterns, parts, isDir); ok {
+		return !excluded
+	}
+	return true
+}
+
+func (g *GitignoreFilter) loadDir(dir []string) error {
+	key := strings.Join(dir, "/")
+	if _, ok := g.loaded[key]; ok {
+		return nil
+	}
+	g.loaded[key] = struct{}{}
+
+	name := path.Join(append(append([]string{}, dir...), ".gitignore")...)
+	file, err := g.fsys.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("loading %s: %w", name, err)
+	}
+	defer file.Close()
+
+	patterns, err := scanPatterns(file, dir)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", name, err)
+	}
+	g.patterns = append(g.patterns, patterns...)
+	return nil
+}
+
+// match reports whether the last pattern matching parts excludes it, and
+// whether any pattern matched at all.
+func match(patterns []gitignore.Pattern, parts []string, isDir bool) (excluded, matched bool) {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		switch patterns[i].Match(parts, isDir) {
+		case gitignore.Exclude:
+			return true, true
+		case gitignore.Include:
+			return false, true
+		}
+	}
+	return false, false
+}
+
+func parsePatterns(lines []string) []gitignore.Pattern {
+	var patterns []gitignore.Pattern
+	for _, pattern := range lines {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(pattern, nil))
+	}
+	return patterns
 }
 
 func loadGitignoreFile(path, baseDir string) ([]gitignore.Pattern, error) {
+	return readPatterns(path, nil)
+}
+
+func readPatterns(path string, domain []string) (patterns []gitignore.Pattern, err error) {
 	file, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -78,8 +201,12 @@ func loadGitignoreFile(path, baseDir string) ([]gitignore.Pattern, error) {
 		}
 	}()
 
+	return scanPatterns(file, domain)
+}
+
+func scanPatterns(r io.Reader, domain []string) ([]gitignore.Pattern, error) {
 	var patterns []gitignore.Pattern
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -88,8 +215,7 @@ func loadGitignoreFile(path, baseDir string) ([]gitignore.Pattern, error) {
 			continue
 		}
 
-		pattern := gitignore.ParsePattern(line, nil)
-		patterns = append(patterns, pattern)
+		patterns = append(patterns, gitignore.ParsePattern(line, domain))
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -106,3 +232,23 @@ func AutoDetectGitignore(baseDir string) string {
 	}
 	return ""
 }
+
+// FindRepoRoot returns the closest directory at or above dir that contains
+// a .git entry, or "" when dir is not inside a git work tree.
+func FindRepoRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(abs, ".git")); err == nil {
+			return abs
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return ""
+		}
+		abs = parent
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 )
 
 func TestGitignoreFilter_CustomPatterns(t *testing.T) {
@@ -245,3 +246,167 @@ temp/*
 		}
 	})
 }
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", rel, err)
+		}
+	}
+}
+
+func TestNestedGitignoreFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		".gitignore":         "*.log\nbuild/\n",
+		"web/.gitignore":     "dist/\n!keep.log\n/local.txt\n",
+		"web/sub/.gitignore": "*.tmp\n",
+	})
+
+	filter, err := NewNestedGitignoreFilter(tmpDir, "", nil)
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"root pattern applies at root", "debug.log", false, false},
+		{"root pattern applies in subdirectory", "api/debug.log", false, false},
+		{"root directory pattern", "build", true, false},
+		{"nested negation overrides root pattern", "web/keep.log", false, true},
+		{"negation is scoped to its directory", "keep.log", false, false},
+		{"nested directory pattern", "web/dist", true, false},
+		{"nested pattern does not leak to siblings", "dist", true, true},
+		{"anchored pattern is relative to its file", "web/local.txt", false, false},
+		{"anchored pattern does not match deeper", "web/sub/local.txt", false, true},
+		{"anchored pattern does not match at root", "local.txt", false, true},
+		{"deeper file applies", "web/sub/x.tmp", false, false},
+		{"deeper file does not apply above it", "web/x.tmp", false, true},
+		{"plain file", "web/index.html", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, filepath.FromSlash(tt.path))
+			mockEntry := &mockDirEntry{name: filepath.Base(path), isDir: tt.isDir}
+
+			if result := filter.ShouldInclude(path, mockEntry); result != tt.expected {
+				t.Errorf("expected %v, got %v for path %s", tt.expected, result, tt.path)
+			}
+		})
+	}
+
+	t.Run("custom patterns take precedence", func(t *testing.T) {
+		filter, err := NewNestedGitignoreFilter(tmpDir, "", []string{"!debug.log", "web/index.html"})
+		if err != nil {
+			t.Fatalf("failed to create filter: %v", err)
+		}
+
+		if !filter.ShouldInclude(filepath.Join(tmpDir, "debug.log"), &mockDirEntry{name: "debug.log"}) {
+			t.Error("custom negation should re-include debug.log")
+		}
+		if filter.ShouldInclude(filepath.Join(tmpDir, "web", "index.html"), &mockDirEntry{name: "index.html"}) {
+			t.Error("custom pattern should exclude web/index.html")
+		}
+	})
+}
+
+func TestNestedGitignoreFilter_RepoRoot(t *testing.T) {
+	repo := t.TempDir()
+	writeTree(t, repo, map[string]string{
+		".git/HEAD":          "ref: refs/heads/main\n",
+		".gitignore":         "*.log\n/web/generated/\n",
+		"web/.gitignore":     "*.tmp\n",
+		"web/app/.gitignore": "!important.log\n",
+	})
+
+	baseDir := filepath.Join(repo, "web")
+	filter, err := NewNestedGitignoreFilter(baseDir, "", nil)
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"debug.log", false, false},
+		{"x.tmp", false, false},
+		{"generated", true, false},
+		{"app/generated", true, true},
+		{"app/important.log", false, true},
+		{"app/main.js", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path := filepath.Join(baseDir, filepath.FromSlash(tt.path))
+			mockEntry := &mockDirEntry{name: filepath.Base(path), isDir: tt.isDir}
+
+			if result := filter.ShouldInclude(path, mockEntry); result != tt.expected {
+				t.Errorf("expected %v, got %v for path %s", tt.expected, result, tt.path)
+			}
+		})
+	}
+}
+
+func TestFSGitignoreFilter(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":         {Data: []byte("*.log\n")},
+		"web/.gitignore":     {Data: []byte("dist/\n")},
+		"web/app/.gitignore": {Data: []byte("!keep.log\n")},
+	}
+
+	baseDir := filepath.Join("checkout", "web")
+	filter, err := NewFSGitignoreFilter(fsys, "web", baseDir, "", nil)
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"debug.log", false, false},
+		{"dist", true, false},
+		{"app/keep.log", false, true},
+		{"app/main.js", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path := filepath.Join(baseDir, filepath.FromSlash(tt.path))
+			mockEntry := &mockDirEntry{name: filepath.Base(path), isDir: tt.isDir}
+
+			if result := filter.ShouldInclude(path, mockEntry); result != tt.expected {
+				t.Errorf("expected %v, got %v for path %s", tt.expected, result, tt.path)
+			}
+		})
+	}
+}
+
+func TestFindRepoRoot(t *testing.T) {
+	repo := t.TempDir()
+	writeTree(t, repo, map[string]string{
+		".git/HEAD":       "ref: refs/heads/main\n",
+		"pkg/sub/file.go": "package sub\n",
+	})
+
+	if root := FindRepoRoot(filepath.Join(repo, "pkg", "sub")); root != repo {
+		t.Errorf("expected %s, got %s", repo, root)
+	}
+	if root := FindRepoRoot(repo); root != repo {
+		t.Errorf("expected %s, got %s", repo, root)
+	}
+}
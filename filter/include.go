@@ -0,0 +1,105 @@
+package filter
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IncludeFilter keeps only the paths matched by at least one glob, relative
+// to baseDir. Globs use path.Match syntax per segment, and a "**" segment
+// matches any number of directories. As in .gitignore files, a glob without
+// a slash matches at any depth, and a glob naming a directory includes
+// everything below it. Directories are only descended into when a glob
+// could match something inside them.
+type IncludeFilter struct {
+	baseDir  string
+	patterns [][]string
+}
+
+func NewIncludeFilter(baseDir string, globs []string) (*IncludeFilter, error) {
+	f := &IncludeFilter{baseDir: baseDir}
+	for _, glob := range globs {
+		glob = strings.TrimSpace(filepath.ToSlash(glob))
+		if glob == "" {
+			continue
+		}
+
+		clean := path.Clean("/" + strings.TrimSuffix(glob, "/"))[1:]
+		if clean == "" {
+			return nil, fmt.Errorf("invalid include pattern %q", glob)
+		}
+		parts := strings.Split(clean, "/")
+		for _, part := range parts {
+			if _, err := path.Match(part, ""); err != nil {
+				return nil, fmt.Errorf("invalid include pattern %q: %w", glob, err)
+			}
+		}
+		if !strings.Contains(strings.TrimSuffix(glob, "/"), "/") {
+			parts = append([]string{"**"}, parts...)
+		}
+		f.patterns = append(f.patterns, parts)
+	}
+	return f, nil
+}
+
+func (f *IncludeFilter) ShouldInclude(p string, d fs.DirEntry) bool {
+	rel := filepath.ToSlash(RelPath(p, f.baseDir))
+	if rel == "." {
+		return true
+	}
+	parts := strings.Split(rel, "/")
+
+	for _, pattern := range f.patterns {
+		for i := len(parts); i > 0; i-- {
+			if matchGlob(pattern, parts[:i]) {
+				return true
+			}
+		}
+		if d.IsDir() && matchGlobPrefix(pattern, parts) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlob(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchGlob(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// matchGlobPrefix reports whether the directory dir could contain a path
+// matched by pattern.
+func matchGlobPrefix(pattern, dir []string) bool {
+	for len(dir) > 0 {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(pattern[0], dir[0]); !ok {
+			return false
+		}
+		pattern, dir = pattern[1:], dir[1:]
+	}
+	return len(pattern) > 0
+}
@@ -0,0 +1,60 @@
+package filter
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIncludeFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		globs    []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"double star file", []string{"src/**/*.go"}, "src/a/b/c.go", false, true},
+		{"double star matches no directories", []string{"src/**/*.go"}, "src/c.go", false, true},
+		{"double star other extension", []string{"src/**/*.go"}, "src/a/c.rs", false, false},
+		{"double star outside prefix", []string{"src/**/*.go"}, "lib/c.go", false, false},
+		{"descend into prefix", []string{"src/**/*.go"}, "src/a", true, true},
+		{"prune other directory", []string{"src/**/*.go"}, "lib", true, false},
+		{"single star segment", []string{"cmd/*/main.go"}, "cmd/tool/main.go", false, true},
+		{"single star does not cross directories", []string{"cmd/*/main.go"}, "cmd/a/b/main.go", false, false},
+		{"descend into single star", []string{"cmd/*/main.go"}, "cmd/tool", true, true},
+		{"prune below single star", []string{"cmd/*/main.go"}, "cmd/tool/sub", true, false},
+		{"directory includes its contents", []string{"filter"}, "filter/sub/x.go", false, true},
+		{"trailing slash directory", []string{"filter/"}, "filter/x.go", false, true},
+		{"nested directory", []string{"pkg/filter"}, "pkg/filter/x.go", false, true},
+		{"nested directory sibling", []string{"pkg/filter"}, "pkg/scanner/x.go", false, false},
+		{"no slash matches at any depth", []string{"*.md"}, "docs/guide/intro.md", false, true},
+		{"no slash descends everywhere", []string{"*.md"}, "docs", true, true},
+		{"leading slash anchors", []string{"/*.md"}, "docs/intro.md", false, false},
+		{"leading slash top level", []string{"/*.md"}, "README.md", false, true},
+		{"any of several globs", []string{"cmd/**", "filter/*.go"}, "filter/dir.go", false, true},
+		{"none of several globs", []string{"cmd/**", "filter/*.go"}, "scanner/scanner.go", false, false},
+		{"base directory", []string{"src/*.go"}, ".", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewIncludeFilter("/project", tt.globs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			path := filepath.Join("/project", filepath.FromSlash(tt.path))
+			entry := &mockDirEntry{name: filepath.Base(path), isDir: tt.isDir}
+			if result := f.ShouldInclude(path, entry); result != tt.expected {
+				t.Errorf("expected %v, got %v for path %s", tt.expected, result, tt.path)
+			}
+		})
+	}
+}
+
+func TestIncludeFilter_InvalidPattern(t *testing.T) {
+	for _, glob := range []string{"src/[a-", "/"} {
+		if _, err := NewIncludeFilter("/project", []string{glob}); err == nil {
+			t.Errorf("expected error for %q", glob)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package filter
+
+import (
+	"io"
+	"io/fs"
+	"path/filepath"
+
+	"amalgo/lang"
+)
+
+// peekLen bounds how much of a file is read to find its "#!" line.
+const peekLen = 256
+
+// MatchShebangs makes the filter also include files without an extension
+// whose "#!" line runs an interpreter of one of languages, named as in the
+// lang catalogue. Only the start of each such file is read, from fsys,
+// which holds the scanned tree as though mounted at baseDir.
+func (e *ExtensionFilter) MatchShebangs(fsys fs.FS, baseDir string, languages map[string]struct{}) {
+	e.fsys = fsys
+	e.baseDir = baseDir
+	e.languages = languages
+}
+
+func (e *ExtensionFilter) matchShebang(path string, d fs.DirEntry) bool {
+	if len(e.languages) == 0 || filepath.Ext(d.Name()) != "" || !d.Type().IsRegular() {
+		return false
+	}
+
+	name := filepath.ToSlash(RelPath(path, e.baseDir))
+	l, ok := lang.ForShebang(peek(e.fsys, name))
+	if !ok {
+		return false
+	}
+	_, want := e.languages[l.Name]
+	return want
+}
+
+// peek returns up to peekLen bytes from the start of the file name in
+// fsys, or nil if it cannot be read.
+func peek(fsys fs.FS, name string) []byte {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	buf := make([]byte, peekLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil
+	}
+	return buf[:n]
+}
@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMatchShebangs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"bin/deploy":       {Data: []byte("#!/usr/bin/env python3\nprint('hi')\n")},
+		"bin/build":        {Data: []byte("#!/bin/bash\r\necho hi\n")},
+		"bin/serve":        {Data: []byte("#!/usr/bin/env node\n")},
+		"bin/notes":        {Data: []byte("just text\n")},
+		"bin/empty":        {Data: nil},
+		"bin/long":         {Data: []byte("#!/usr/bin/env python3 " + strings.Repeat("x", 1000))},
+		"bin/tool.sh":      {Data: []byte("#!/usr/bin/env python3\n")},
+		"lib/app.py":       {Data: []byte("import os\n")},
+		"lib/view.js":      {Data: []byte("#!/usr/bin/env python3\n")},
+		"scripts/Makefile": {Data: []byte("#!/usr/bin/make -f\n")},
+	}
+
+	f := NewNameFilter(nil, map[string]struct{}{".py": {}})
+	f.MatchShebangs(fsys, "/project", map[string]struct{}{"python": {}, "shell": {}})
+
+	var included []string
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && f.ShouldInclude("/project/"+path, d) {
+			included = append(included, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+
+	want := []string{"bin/build", "bin/deploy", "bin/long", "lib/app.py"}
+	if !reflect.DeepEqual(included, want) {
+		t.Errorf("expected %v, got %v", want, included)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	fsys := fstest.MapFS{
+		"short": {Data: []byte("#!/bin/sh")},
+		"long":  {Data: []byte(strings.Repeat("a", 1000))},
+	}
+
+	tests := map[string]string{
+		"short":   "#!/bin/sh",
+		"long":    strings.Repeat("a", peekLen),
+		"missing": "",
+	}
+	for name, want := range tests {
+		if got := string(peek(fsys, name)); got != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+}
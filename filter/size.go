@@ -0,0 +1,34 @@
+package filter
+
+import (
+	"io/fs"
+)
+
+// SizeFilter excludes files larger than maxSize bytes, as reported by
+// fs.DirEntry.Info. The paths it excludes are kept in Skipped so that they
+// can be reported. Files whose size cannot be read are included, leaving
+// the error to whoever reads them.
+type SizeFilter struct {
+	maxSize int64
+	Skipped []string
+}
+
+func NewSizeFilter(maxSize int64) *SizeFilter {
+	return &SizeFilter{
+		maxSize: maxSize,
+	}
+}
+
+func (s *SizeFilter) ShouldInclude(path string, d fs.DirEntry) bool {
+	if d.IsDir() {
+		return true
+	}
+
+	info, err := d.Info()
+	if err != nil || info == nil || info.Size() <= s.maxSize {
+		return true
+	}
+
+	s.Skipped = append(s.Skipped, path)
+	return false
+}
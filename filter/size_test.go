@@ -0,0 +1,45 @@
+package filter
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSizeFilter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"small.go":     {Data: []byte("package a\n")},
+		"exact.go":     {Data: []byte(strings.Repeat("x", 16))},
+		"big.js":       {Data: []byte(strings.Repeat("x", 17))},
+		"dir/huge.txt": {Data: []byte(strings.Repeat("x", 100))},
+	}
+
+	filter := NewSizeFilter(16)
+
+	var included []string
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filter.ShouldInclude(path, d) && !d.IsDir() {
+			included = append(included, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+
+	if want := []string{"exact.go", "small.go"}; !reflect.DeepEqual(included, want) {
+		t.Errorf("expected %v to be included, got %v", want, included)
+	}
+	if want := []string{"big.js", "dir/huge.txt"}; !reflect.DeepEqual(filter.Skipped, want) {
+		t.Errorf("expected %v to be skipped, got %v", want, filter.Skipped)
+	}
+
+	if !filter.ShouldInclude("deleted.go", pathEntry{name: "deleted.go"}) {
+		t.Error("expected file without size info to be included")
+	}
+}
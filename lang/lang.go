@@ -0,0 +1,185 @@
+package lang
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Language describes how files of one language are recognised, in the
+// spirit of GitHub's linguist: by extension, by exact file name, or by the
+// interpreter named on a shebang line. Fence is the info string used for
+// fenced code blocks.
+type Language struct {
+	Name         string
+	Fence        string
+	Extensions   []string
+	Filenames    []string
+	Interpreters []string
+}
+
+var catalogue = []Language{
+	{Name: "awk", Fence: "awk", Extensions: []string{".awk"}, Interpreters: []string{"awk", "gawk", "mawk"}},
+	{Name: "blade", Fence: "blade", Extensions: []string{".blade.php"}},
+	{Name: "c", Fence: "c", Extensions: []string{".c", ".h"}},
+	{Name: "cmake", Fence: "cmake", Extensions: []string{".cmake"}, Filenames: []string{"CMakeLists.txt"}},
+	{Name: "cpp", Fence: "cpp", Extensions: []string{".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx"}},
+	{Name: "csharp", Fence: "csharp", Extensions: []string{".cs"}},
+	{Name: "css", Fence: "css", Extensions: []string{".css"}},
+	{Name: "dockerfile", Fence: "dockerfile", Extensions: []string{".dockerfile"}, Filenames: []string{"Dockerfile", "Containerfile"}},
+	{Name: "erb", Fence: "erb", Extensions: []string{".erb", ".html.erb"}},
+	{Name: "go", Fence: "go", Extensions: []string{".go"}},
+	{Name: "go-checksums", Fence: "go-sum", Filenames: []string{"go.sum", "go.work.sum"}},
+	{Name: "go-module", Fence: "go-mod", Filenames: []string{"go.mod", "go.work"}},
+	{Name: "groovy", Fence: "groovy", Extensions: []string{".groovy", ".gradle"}, Filenames: []string{"Jenkinsfile"}},
+	{Name: "html", Fence: "html", Extensions: []string{".html", ".htm"}},
+	{Name: "java", Fence: "java", Extensions: []string{".java"}},
+	{Name: "javascript", Fence: "javascript", Extensions: []string{".js", ".mjs", ".cjs", ".jsx"}, Interpreters: []string{"node", "nodejs"}},
+	{Name: "json", Fence: "json", Extensions: []string{".json"}},
+	{Name: "kotlin", Fence: "kotlin", Extensions: []string{".kt", ".kts"}},
+	{Name: "lua", Fence: "lua", Extensions: []string{".lua"}, Interpreters: []string{"lua", "luajit"}},
+	{Name: "makefile", Fence: "makefile", Extensions: []string{".mk"}, Filenames: []string{"Makefile", "GNUmakefile", "makefile"}, Interpreters: []string{"make"}},
+	{Name: "markdown", Fence: "markdown", Extensions: []string{".md", ".markdown"}},
+	{Name: "perl", Fence: "perl", Extensions: []string{".pl", ".pm"}, Interpreters: []string{"perl"}},
+	{Name: "php", Fence: "php", Extensions: []string{".php"}, Interpreters: []string{"php"}},
+	{Name: "python", Fence: "python", Extensions: []string{".py", ".pyi", ".pyw"}, Interpreters: []string{"python", "python2", "python3"}},
+	{Name: "ruby", Fence: "ruby", Extensions: []string{".rb"}, Filenames: []string{"Gemfile", "Rakefile", "Vagrantfile"}, Interpreters: []string{"ruby"}},
+	{Name: "rust", Fence: "rust", Extensions: []string{".rs"}},
+	{Name: "scss", Fence: "scss", Extensions: []string{".scss"}},
+	{Name: "shell", Fence: "bash", Extensions: []string{".sh", ".bash", ".zsh"}, Filenames: []string{".bashrc", ".bash_profile", ".zshrc", ".profile"}, Interpreters: []string{"sh", "bash", "zsh", "dash", "ksh"}},
+	{Name: "sql", Fence: "sql", Extensions: []string{".sql"}},
+	{Name: "swift", Fence: "swift", Extensions: []string{".swift"}, Interpreters: []string{"swift"}},
+	{Name: "text", Fence: "text", Extensions: []string{".txt"}},
+	{Name: "toml", Fence: "toml", Extensions: []string{".toml"}, Filenames: []string{"Cargo.lock", "Pipfile"}},
+	{Name: "typescript", Fence: "typescript", Extensions: []string{".ts", ".mts", ".cts", ".tsx", ".d.ts"}, Interpreters: []string{"deno", "ts-node", "tsx"}},
+	{Name: "xml", Fence: "xml", Extensions: []string{".xml"}},
+	{Name: "yaml", Fence: "yaml", Extensions: []string{".yaml", ".yml"}},
+}
+
+var (
+	byName        = make(map[string]*Language)
+	byExtension   = make(map[string]*Language)
+	byFilename    = make(map[string]*Language)
+	byInterpreter = make(map[string]*Language)
+)
+
+func init() {
+	for i := range catalogue {
+		l := &catalogue[i]
+		byName[l.Name] = l
+		for _, ext := range l.Extensions {
+			byExtension[ext] = l
+		}
+		for _, name := range l.Filenames {
+			byFilename[name] = l
+		}
+		for _, interp := range l.Interpreters {
+			byInterpreter[interp] = l
+		}
+	}
+}
+
+// All returns the whole catalogue, sorted by name.
+func All() []Language {
+	return append([]Language(nil), catalogue...)
+}
+
+// Names returns the names of all languages, sorted.
+func Names() []string {
+	names := make([]string, 0, len(catalogue))
+	for _, l := range catalogue {
+		names = append(names, l.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup finds a language by name, ignoring case.
+func Lookup(name string) (Language, bool) {
+	return found(byName[strings.ToLower(strings.TrimSpace(name))])
+}
+
+// ForExtension finds the language of an extension such as ".py", ignoring
+// case. The leading dot is optional.
+func ForExtension(ext string) (Language, bool) {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return found(byExtension[ext])
+}
+
+// ForFilename finds the language of a file from its base name: first by
+// the exact name, then by each of its suffixes from the longest, so that
+// "types.d.ts" is matched before "types.ts" would be.
+func ForFilename(name string) (Language, bool) {
+	name = path.Base(name)
+	if l, ok := byFilename[name]; ok {
+		return *l, true
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		if l, ok := ForExtension(name[i:]); ok {
+			return l, true
+		}
+	}
+	return Language{}, false
+}
+
+// ForInterpreter finds the language run by an interpreter such as "python3"
+// or "python3.12"; version numbers are ignored when the exact name is not
+// known.
+func ForInterpreter(name string) (Language, bool) {
+	if l, ok := byInterpreter[name]; ok {
+		return *l, true
+	}
+	return found(byInterpreter[strings.TrimRight(name, "0123456789.-")])
+}
+
+// Interpreter returns the interpreter named by a "#!" line, following
+// /usr/bin/env and skipping its options, or "" if line is not a shebang.
+func Interpreter(line string) string {
+	rest, ok := strings.CutPrefix(line, "#!")
+	if !ok {
+		return ""
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return ""
+	}
+	interp := path.Base(fields[0])
+	if interp != "env" {
+		return interp
+	}
+
+	for _, arg := range fields[1:] {
+		if strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+			continue
+		}
+		return path.Base(arg)
+	}
+	return ""
+}
+
+// ForShebang finds the language of a script from the "#!" line at the start
+// of content. Only the first line is looked at, so content may be just the
+// first few hundred bytes of the file.
+func ForShebang(content []byte) (Language, bool) {
+	line, _, _ := bytes.Cut(content, []byte{'\n'})
+	interp := Interpreter(string(bytes.TrimRight(line, "\r")))
+	if interp == "" {
+		return Language{}, false
+	}
+	return ForInterpreter(interp)
+}
+
+func found(l *Language) (Language, bool) {
+	if l == nil {
+		return Language{}, false
+	}
+	return *l, true
+}
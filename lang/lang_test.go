@@ -0,0 +1,144 @@
+package lang
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestCatalogue(t *testing.T) {
+	if !sort.SliceIsSorted(catalogue, func(i, j int) bool { return catalogue[i].Name < catalogue[j].Name }) {
+		t.Error("catalogue should be sorted by name")
+	}
+
+	seen := map[string]string{}
+	for _, l := range catalogue {
+		if l.Fence == "" {
+			t.Errorf("%s: missing fence", l.Name)
+		}
+		var keys []string
+		for _, ext := range l.Extensions {
+			keys = append(keys, "extension "+ext)
+		}
+		for _, name := range l.Filenames {
+			keys = append(keys, "filename "+name)
+		}
+		for _, interp := range l.Interpreters {
+			keys = append(keys, "interpreter "+interp)
+		}
+		for _, key := range keys {
+			if other, ok := seen[key]; ok {
+				t.Errorf("%s is claimed by both %s and %s", key, other, l.Name)
+			}
+			seen[key] = l.Name
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	l, ok := Lookup(" Python ")
+	if !ok || l.Name != "python" {
+		t.Fatalf("expected python, got %+v, %t", l, ok)
+	}
+	if want := []string{".py", ".pyi", ".pyw"}; len(l.Extensions) != len(want) {
+		t.Errorf("expected extensions %v, got %v", want, l.Extensions)
+	}
+	if _, ok := Lookup("cobol"); ok {
+		t.Error("expected unknown language not to be found")
+	}
+}
+
+func TestForFilename(t *testing.T) {
+	tests := []struct {
+		name  string
+		fence string
+	}{
+		{"main.go", "go"},
+		{"MAIN.GO", "go"},
+		{"pkg/util.py", "python"},
+		{"stubs.pyi", "python"},
+		{"Makefile", "makefile"},
+		{"build/Dockerfile", "dockerfile"},
+		{"go.mod", "go-mod"},
+		{"go.sum", "go-sum"},
+		{"CMakeLists.txt", "cmake"},
+		{"notes.txt", "text"},
+		{"types.d.ts", "typescript"},
+		{"index.html.erb", "erb"},
+		{"view.blade.php", "blade"},
+		{".bashrc", "bash"},
+		{"LICENSE", ""},
+		{".gitignore", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, ok := ForFilename(tt.name)
+			if ok != (tt.fence != "") || l.Fence != tt.fence {
+				t.Errorf("expected fence %q, got %q (found %t)", tt.fence, l.Fence, ok)
+			}
+		})
+	}
+}
+
+func TestForExtension(t *testing.T) {
+	for ext, name := range map[string]string{".go": "go", "GO": "go", ".H": "c", "tsx": "typescript"} {
+		if l, ok := ForExtension(ext); !ok || l.Name != name {
+			t.Errorf("%s: expected %s, got %q", ext, name, l.Name)
+		}
+	}
+	if _, ok := ForExtension(".unknown"); ok {
+		t.Error("expected unknown extension not to be found")
+	}
+}
+
+func TestInterpreter(t *testing.T) {
+	tests := []struct {
+		line   string
+		interp string
+		lang   string
+	}{
+		{"#!/usr/bin/env python3", "python3", "python"},
+		{"#!/usr/bin/python3.12 -u", "python3.12", "python"},
+		{"#!/bin/bash -e", "bash", "shell"},
+		{"#! /bin/sh", "sh", "shell"},
+		{"#!/usr/bin/env -S node --no-warnings", "node", "javascript"},
+		{"#!/usr/bin/env LANG=C perl", "perl", "perl"},
+		{"#!/usr/bin/env", "", ""},
+		{"#!/opt/bin/unknown", "unknown", ""},
+		{"# not a shebang", "", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			interp := Interpreter(tt.line)
+			if interp != tt.interp {
+				t.Errorf("expected interpreter %q, got %q", tt.interp, interp)
+			}
+			l, _ := ForInterpreter(interp)
+			if l.Name != tt.lang {
+				t.Errorf("expected language %q, got %q", tt.lang, l.Name)
+			}
+		})
+	}
+}
+
+func TestForShebang(t *testing.T) {
+	tests := []struct {
+		content string
+		lang    string
+	}{
+		{"#!/usr/bin/env python3\nimport os\n", "python"},
+		{"#!/bin/sh\r\necho hi\r\n", "shell"},
+		{"#!/usr/bin/env ruby", "ruby"},
+		{"echo hi\n#!/bin/sh\n", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		l, ok := ForShebang([]byte(tt.content))
+		if ok != (tt.lang != "") || l.Name != tt.lang {
+			t.Errorf("%q: expected %q, got %q (found %t)", tt.content, tt.lang, l.Name, ok)
+		}
+	}
+}
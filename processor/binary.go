@@ -0,0 +1,105 @@
+package processor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	BinarySkip        = "skip"
+	BinaryPlaceholder = "placeholder"
+	BinaryBase64      = "base64"
+)
+
+var BinaryPolicies = []string{BinarySkip, BinaryPlaceholder, BinaryBase64}
+
+const (
+	// Only the start of a file is inspected, as git does.
+	sniffLen = 8000
+	// Files with more than this share of invalid UTF-8 bytes are binary.
+	maxInvalidUTF8 = 0.1
+	base64LineLen  = 76
+)
+
+// DetectBinary reports whether content looks like binary data rather than
+// text, along with its MIME type as guessed by http.DetectContentType. Only
+// NUL bytes and invalid UTF-8 decide: the MIME type is a label, since text
+// that happens to start with a magic number such as "BM" or "%PDF-" is
+// still text.
+func DetectBinary(content []byte) (bool, string) {
+	sample := content[:min(len(content), sniffLen)]
+	mime := http.DetectContentType(sample)
+
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true, mime
+	}
+
+	invalid := 0
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRune(sample[i:])
+		if r == utf8.RuneError && size == 1 {
+			// A rune cut off by the end of the sample is not an error.
+			if len(sample) < len(content) && !utf8.FullRune(sample[i:]) {
+				break
+			}
+			invalid++
+		}
+		i += size
+	}
+	return float64(invalid) > maxInvalidUTF8*float64(len(sample)), mime
+}
+
+// HandleBinary applies policy to file when its content looks binary. Skipped
+// files are reported with false; otherwise the content is replaced by a
+// placeholder or its base64 encoding and the file is marked Binary, with
+// the hash of the original content. Files that could not be read are
+// passed through untouched.
+func HandleBinary(file FileInfo, policy string) (FileInfo, bool, error) {
+	if file.Err != nil {
+		return file, true, nil
+	}
+	binary, mime := DetectBinary(file.Content)
+	if !binary {
+		return file, true, nil
+	}
+
+	if policy == BinarySkip {
+		return file, false, nil
+	}
+	if file.SHA256 == "" {
+		sum := sha256.Sum256(file.Content)
+		file.SHA256 = hex.EncodeToString(sum[:])
+	}
+	file.Binary = true
+
+	switch policy {
+	case BinaryPlaceholder:
+		file.Content = []byte(fmt.Sprintf("[binary file: %d bytes, %s]\n", len(file.Content), mime))
+	case BinaryBase64:
+		file.Content = encodeBase64(file.Content)
+	default:
+		return file, false, fmt.Errorf("unknown binary policy: %s (expected one of %s)", policy, strings.Join(BinaryPolicies, ", "))
+	}
+	return file, true, nil
+}
+
+// encodeBase64 returns content in standard base64, wrapped as in MIME.
+func encodeBase64(content []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(content)
+
+	var out bytes.Buffer
+	for len(encoded) > base64LineLen {
+		out.WriteString(encoded[:base64LineLen])
+		out.WriteByte('\n')
+		encoded = encoded[base64LineLen:]
+	}
+	out.WriteString(encoded)
+	out.WriteByte('\n')
+	return out.Bytes()
+}
@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestDetectBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		binary  bool
+		mime    string
+	}{
+		{"empty", nil, false, "text/plain; charset=utf-8"},
+		{"go source", []byte("package main\n\nfunc main() {}\n"), false, "text/plain; charset=utf-8"},
+		{"utf-8 text", []byte("héllo wörld ✓\n"), false, "text/plain; charset=utf-8"},
+		{"html", []byte("<html><body></body></html>"), false, "text/html; charset=utf-8"},
+		{"nul byte", []byte("abc\x00def"), true, "application/octet-stream"},
+		{"pdf", []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n" + strings.Repeat("\x80\x81", 4)), true, "application/pdf"},
+		{"text with pdf magic", []byte("%PDF-1.4\n%âãÏÓ\n"), false, "application/pdf"},
+		{"text with bmp magic", []byte("BMI calculator\n"), false, "image/bmp"},
+		{"text with mp3 magic", []byte("ID3 tags explained\n"), false, "audio/mpeg"},
+		{"wasm", []byte("\x00asm\x01\x00\x00\x00"), true, "application/wasm"},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), true, "image/png"},
+		{"invalid utf-8", bytes.Repeat([]byte("a\xff"), 100), true, "text/plain; charset=utf-8"},
+		{"rune cut by sample", append(bytes.Repeat([]byte("a"), sniffLen-1), "é"...), false, "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binary, mime := DetectBinary(tt.content)
+			if binary != tt.binary {
+				t.Errorf("expected binary=%t, got %t", tt.binary, binary)
+			}
+			if mime != tt.mime {
+				t.Errorf("expected mime %q, got %q", tt.mime, mime)
+			}
+		})
+	}
+}
+
+func TestHandleBinary(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	text := FileInfo{RelPath: "a.txt", Content: []byte("hello\n")}
+	image := FileInfo{RelPath: "logo.png", Content: png}
+
+	for _, policy := range BinaryPolicies {
+		got, keep, err := HandleBinary(text, policy)
+		if err != nil || !keep || string(got.Content) != "hello\n" {
+			t.Errorf("%s: expected text to pass through, got %q keep=%t err=%v", policy, got.Content, keep, err)
+		}
+	}
+
+	if _, keep, err := HandleBinary(image, BinarySkip); err != nil || keep {
+		t.Errorf("skip: expected file to be dropped, got keep=%t err=%v", keep, err)
+	}
+
+	got, keep, err := HandleBinary(image, BinaryPlaceholder)
+	if err != nil || !keep {
+		t.Fatalf("placeholder: expected file to be kept, got keep=%t err=%v", keep, err)
+	}
+	if want := "[binary file: 16 bytes, image/png]\n"; string(got.Content) != want {
+		t.Errorf("placeholder: expected %q, got %q", want, got.Content)
+	}
+	if want := "02a3e298f1533f62558c58e4c70edcab9af5a50d62d925fd5390942020fb0fb8"; !got.Binary || got.SHA256 != want {
+		t.Errorf("placeholder: expected a Binary file hashed %s, got Binary=%t %s", want, got.Binary, got.SHA256)
+	}
+
+	got, keep, err = HandleBinary(image, BinaryBase64)
+	if err != nil || !keep {
+		t.Fatalf("base64: expected file to be kept, got keep=%t err=%v", keep, err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(got.Content), "\n", ""))
+	if err != nil || !bytes.Equal(decoded, png) {
+		t.Errorf("base64: content does not decode to the original: %q", got.Content)
+	}
+
+	if _, _, err := HandleBinary(image, "bogus"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestEncodeBase64(t *testing.T) {
+	encoded := encodeBase64(bytes.Repeat([]byte{0xff}, 120))
+	lines := strings.Split(strings.TrimSuffix(string(encoded), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %q", len(lines), encoded)
+	}
+	for _, line := range lines[:2] {
+		if len(line) != base64LineLen {
+			t.Errorf("expected line of %d characters, got %d", base64LineLen, len(line))
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"amalgo/tokenizer"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+const (
+	OrderDepth = "depth"
+	OrderSize  = "size"
+	OrderPath  = "path"
+)
+
+var BudgetOrders = []string{OrderDepth, OrderSize, OrderPath}
+
+const (
+	// Rough per-file cost of the heading and fences around each file.
+	fileOverheadTokens = 12
+	// Tokens held back for the heading of the trailing manifest, plus the
+	// rough cost of each line in it.
+	manifestReserveTokens = 32
+	manifestLineTokens    = 8
+	// Files are only truncated when at least this many tokens of content fit.
+	minTruncateTokens = 32
+)
+
+var entrypoints = map[string]struct{}{
+	"main.go": {}, "main.rs": {}, "lib.rs": {}, "main.py": {}, "__main__.py": {},
+	"app.py": {}, "manage.py": {}, "index.js": {}, "index.ts": {}, "main.js": {},
+	"main.ts": {}, "main.c": {}, "main.cpp": {}, "main.java": {}, "program.cs": {},
+}
+
+type Budget struct {
+	MaxTokens int
+	Order     string
+	Priority  []string
+	Truncate  bool
+}
+
+type OmittedFile struct {
+	RelPath   string
+	Tokens    int
+	Truncated bool
+}
+
+type budgetEntry struct {
+	tokens int
+	cost   int
+}
+
+// ProcessWithBudget renders files with p while keeping the output within
+// b.MaxTokens. Files are admitted in priority order; those that do not fit
+// are dropped or truncated and listed in opts.Omitted so that processors
+// can emit a trailing manifest. When the manifest itself would not fit,
+// the dropped files it has no room for are only counted, in
+// opts.Unlisted. Along with the output it returns the files written,
+// truncated ones as cut, and every omitted file.
+func ProcessWithBudget(p Processor, files []FileInfo, opts Options, b Budget, enc tokenizer.Encoder) ([]byte, []FileInfo, []OmittedFile, error) {
+	order, err := b.rank(files)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	entries := make([]budgetEntry, len(files))
+	for i, file := range files {
+		tokens := enc.Count(file.Content)
+		entries[i] = budgetEntry{
+			tokens: tokens,
+			cost:   tokens + enc.Count([]byte(file.RelPath)) + fileOverheadTokens,
+		}
+	}
+
+	// Files are admitted greedily; only the files left out are charged
+	// for a line in the manifest, from whatever the admitted files leave.
+	remaining := b.MaxTokens - manifestReserveTokens
+	manifestCost := make([]int, len(files))
+	for i, file := range files {
+		manifestCost[i] = enc.Count([]byte(file.RelPath)) + manifestLineTokens
+	}
+
+	kept := make(map[int]FileInfo, len(files))
+	var keptOrder []int
+	omitted := make(map[int]OmittedFile)
+	truncatedTo := 0
+
+	for _, i := range order {
+		e := entries[i]
+
+		if e.cost <= remaining {
+			kept[i] = files[i]
+			keptOrder = append(keptOrder, i)
+			remaining -= e.cost
+			continue
+		}
+
+		// A truncated file is listed in the manifest as well.
+		overhead := e.cost - e.tokens
+		if available := remaining - overhead - manifestCost[i]; b.Truncate && available >= minTruncateTokens {
+			truncated, dropped := truncateToTokens(files[i], available, enc)
+			kept[i] = truncated
+			keptOrder = append(keptOrder, i)
+			omitted[i] = OmittedFile{RelPath: files[i].RelPath, Tokens: dropped, Truncated: true}
+			remaining -= overhead + available + manifestCost[i]
+			truncatedTo = available
+			continue
+		}
+
+		omitted[i] = OmittedFile{RelPath: files[i].RelPath, Tokens: e.tokens}
+	}
+
+	for {
+		selected := make([]FileInfo, 0, len(kept))
+		for i := range files {
+			if f, ok := kept[i]; ok {
+				selected = append(selected, f)
+			}
+		}
+
+		// Truncated files were paid for on admission. Dropped files are
+		// listed while there is room, and only counted after that.
+		all := make([]OmittedFile, 0, len(omitted))
+		var listed []OmittedFile
+		unlisted, room := 0, remaining
+		for i := range files {
+			o, ok := omitted[i]
+			if !ok {
+				continue
+			}
+			all = append(all, o)
+			switch {
+			case o.Truncated:
+				listed = append(listed, o)
+			case manifestCost[i] <= room:
+				listed = append(listed, o)
+				room -= manifestCost[i]
+			default:
+				unlisted++
+			}
+		}
+
+		opts.Omitted = listed
+		opts.Unlisted = unlisted
+		opts.MaxTokens = b.MaxTokens
+		out, err := p.Process(selected, opts)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		excess := enc.Count(out) - b.MaxTokens
+		if excess <= 0 || len(keptOrder) == 0 {
+			return out, selected, all, nil
+		}
+
+		// The estimate was too optimistic by excess. Free that in one step:
+		// shrink a truncated file further while that is still worthwhile,
+		// otherwise drop files from the lowest priority up until the budget
+		// balances again. Rendering again only confirms this.
+		remaining -= excess
+		last := keptOrder[len(keptOrder)-1]
+		if o := omitted[last]; o.Truncated && truncatedTo-excess >= minTruncateTokens {
+			truncatedTo -= excess
+			remaining += excess
+			truncated, dropped := truncateToTokens(files[last], truncatedTo, enc)
+			kept[last] = truncated
+			omitted[last] = OmittedFile{RelPath: o.RelPath, Tokens: dropped, Truncated: true}
+			continue
+		}
+		for remaining < 0 && len(keptOrder) > 0 {
+			last := keptOrder[len(keptOrder)-1]
+			keptOrder = keptOrder[:len(keptOrder)-1]
+			delete(kept, last)
+
+			if omitted[last].Truncated {
+				remaining += entries[last].cost - entries[last].tokens + truncatedTo + manifestCost[last]
+			} else {
+				remaining += entries[last].cost
+			}
+			omitted[last] = OmittedFile{RelPath: files[last].RelPath, Tokens: entries[last].tokens}
+		}
+	}
+}
+
+// rank returns file indices from highest to lowest priority: explicit
+// priority globs in the order given, then READMEs, then entrypoints, then
+// the configured order.
+func (b Budget) rank(files []FileInfo) ([]int, error) {
+	var patterns []gitignore.Pattern
+	for _, glob := range b.Priority {
+		glob = strings.TrimSpace(glob)
+		if glob != "" {
+			patterns = append(patterns, gitignore.ParsePattern(glob, nil))
+		}
+	}
+
+	tier := func(f FileInfo) int {
+		parts := strings.Split(filepath.ToSlash(f.RelPath), "/")
+		for i, p := range patterns {
+			if p.Match(parts, false) == gitignore.Exclude {
+				return i
+			}
+		}
+
+		name := strings.ToLower(filepath.Base(f.RelPath))
+		if strings.HasPrefix(name, "readme") {
+			return len(patterns)
+		}
+		if _, ok := entrypoints[name]; ok {
+			return len(patterns) + 1
+		}
+		return len(patterns) + 2
+	}
+
+	var less func(a, b FileInfo) bool
+	switch b.Order {
+	case OrderDepth, "":
+		less = func(a, b FileInfo) bool {
+			return pathDepth(a.RelPath) < pathDepth(b.RelPath)
+		}
+	case OrderSize:
+		less = func(a, b FileInfo) bool {
+			return len(a.Content) < len(b.Content)
+		}
+	case OrderPath:
+		less = func(a, b FileInfo) bool { return false }
+	default:
+		return nil, fmt.Errorf("unknown priority order: %s (expected one of %s)", b.Order, strings.Join(BudgetOrders, ", "))
+	}
+
+	tiers := make([]int, len(files))
+	order := make([]int, len(files))
+	for i, f := range files {
+		tiers[i] = tier(f)
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(x, y int) bool {
+		i, j := order[x], order[y]
+		if tiers[i] != tiers[j] {
+			return tiers[i] < tiers[j]
+		}
+		return less(files[i], files[j])
+	})
+
+	return order, nil
+}
+
+func pathDepth(relPath string) int {
+	return strings.Count(filepath.ToSlash(relPath), "/")
+}
+
+// truncateToTokens keeps the longest run of whole lines that fits in
+// maxTokens and appends a marker. It returns the truncated file and the
+// number of tokens dropped.
+func truncateToTokens(file FileInfo, maxTokens int, enc tokenizer.Encoder) (FileInfo, int) {
+	total := enc.Count(file.Content)
+	maxTokens -= enc.Count([]byte(fmt.Sprintf("... [truncated %d tokens]\n", total)))
+
+	lineEnds := []int{0}
+	for i, b := range file.Content {
+		if b == '\n' {
+			lineEnds = append(lineEnds, i+1)
+		}
+	}
+	if lineEnds[len(lineEnds)-1] != len(file.Content) {
+		lineEnds = append(lineEnds, len(file.Content))
+	}
+
+	lo, hi := 0, len(lineEnds)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if enc.Count(file.Content[:lineEnds[mid]]) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	kept := file.Content[:lineEnds[lo]]
+	dropped := total - enc.Count(kept)
+
+	var content bytes.Buffer
+	content.Write(kept)
+	if len(kept) > 0 && kept[len(kept)-1] != '\n' {
+		content.WriteByte('\n')
+	}
+	fmt.Fprintf(&content, "... [truncated %d tokens]\n", dropped)
+
+	file.Content = content.Bytes()
+	return file, dropped
+}
@@ -0,0 +1,251 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"amalgo/tokenizer"
+)
+
+func budgetFiles() []FileInfo {
+	return []FileInfo{
+		{RelPath: "README.md", Content: bytes.Repeat([]byte("readme line\n"), 10), Ext: ".md"},
+		{RelPath: "cmd/app/main.go", Content: bytes.Repeat([]byte("package main\n"), 10), Ext: ".go"},
+		{RelPath: "internal/deep/big.go", Content: bytes.Repeat([]byte("// filler filler\n"), 200), Ext: ".go"},
+		{RelPath: "util.go", Content: bytes.Repeat([]byte("package util\n"), 10), Ext: ".go"},
+	}
+}
+
+func TestBudget_Rank(t *testing.T) {
+	files := []FileInfo{
+		{RelPath: "z/deep/a.go", Content: []byte("a")},
+		{RelPath: "b.go", Content: []byte("bbbbbbbb")},
+		{RelPath: "main.go", Content: []byte("main")},
+		{RelPath: "docs/README.md", Content: []byte("readme")},
+		{RelPath: "c.go", Content: []byte("cc")},
+	}
+
+	tests := []struct {
+		name     string
+		budget   Budget
+		expected []string
+	}{
+		{
+			name:     "depth order",
+			budget:   Budget{Order: OrderDepth},
+			expected: []string{"docs/README.md", "main.go", "b.go", "c.go", "z/deep/a.go"},
+		},
+		{
+			name:     "size order",
+			budget:   Budget{Order: OrderSize},
+			expected: []string{"docs/README.md", "main.go", "z/deep/a.go", "c.go", "b.go"},
+		},
+		{
+			name:     "path order",
+			budget:   Budget{Order: OrderPath},
+			expected: []string{"docs/README.md", "main.go", "z/deep/a.go", "b.go", "c.go"},
+		},
+		{
+			name:     "priority globs come first",
+			budget:   Budget{Order: OrderDepth, Priority: []string{"c.go", "z/**"}},
+			expected: []string{"c.go", "z/deep/a.go", "docs/README.md", "main.go", "b.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := tt.budget.rank(files)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, i := range order {
+				got = append(got, files[i].RelPath)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("unknown order", func(t *testing.T) {
+		if _, err := (Budget{Order: "random"}).rank(files); err == nil {
+			t.Error("expected error for unknown order")
+		}
+	})
+}
+
+func TestProcessWithBudget(t *testing.T) {
+	enc := tokenizer.NewHeuristic()
+	proc := NewMarkdownProcessor()
+
+	t.Run("everything fits", func(t *testing.T) {
+		files := budgetFiles()
+		out, _, omitted, err := ProcessWithBudget(proc, files, Options{HeadingLevel: 1}, Budget{MaxTokens: 100000}, enc)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(omitted) != 0 {
+			t.Errorf("expected nothing omitted, got %+v", omitted)
+		}
+		if strings.Contains(string(out), "Omitted files") {
+			t.Error("expected no manifest")
+		}
+	})
+
+	t.Run("drops low priority files", func(t *testing.T) {
+		files := budgetFiles()
+		budget := Budget{MaxTokens: 300, Order: OrderDepth}
+
+		out, _, omitted, err := ProcessWithBudget(proc, files, Options{HeadingLevel: 1}, budget, enc)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if enc.Count(out) > budget.MaxTokens {
+			t.Errorf("output has %d tokens, budget is %d", enc.Count(out), budget.MaxTokens)
+		}
+		if len(omitted) != 1 || omitted[0].RelPath != "internal/deep/big.go" || omitted[0].Truncated {
+			t.Fatalf("expected big.go to be omitted, got %+v", omitted)
+		}
+
+		output := string(out)
+		for _, want := range []string{"# README.md", "# cmd/app/main.go", "# util.go", "# Omitted files", "`internal/deep/big.go`", "300 token budget"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected output to contain %q", want)
+			}
+		}
+		if strings.Index(output, "# README.md") > strings.Index(output, "# util.go") {
+			t.Error("kept files should stay in their original order")
+		}
+	})
+
+	t.Run("truncates when enabled", func(t *testing.T) {
+		files := budgetFiles()
+		budget := Budget{MaxTokens: 600, Order: OrderDepth, Truncate: true}
+
+		out, _, omitted, err := ProcessWithBudget(proc, files, Options{HeadingLevel: 1}, budget, enc)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if enc.Count(out) > budget.MaxTokens {
+			t.Errorf("output has %d tokens, budget is %d", enc.Count(out), budget.MaxTokens)
+		}
+		if len(omitted) != 1 || !omitted[0].Truncated || omitted[0].Tokens <= 0 {
+			t.Fatalf("expected big.go to be truncated, got %+v", omitted)
+		}
+
+		output := string(out)
+		if !strings.Contains(output, "# internal/deep/big.go") {
+			t.Error("expected truncated file to be present")
+		}
+		if !strings.Contains(output, "... [truncated ") {
+			t.Error("expected truncation marker")
+		}
+	})
+
+	t.Run("tiny budget omits everything", func(t *testing.T) {
+		files := budgetFiles()
+		out, _, omitted, err := ProcessWithBudget(proc, files, Options{HeadingLevel: 1}, Budget{MaxTokens: 10}, enc)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(omitted) != len(files) {
+			t.Errorf("expected all files omitted, got %d", len(omitted))
+		}
+		if !strings.Contains(string(out), "No files found") {
+			t.Error("expected empty bundle message")
+		}
+	})
+}
+
+func TestProcessWithBudget_ManySmallFiles(t *testing.T) {
+	files := []FileInfo{{RelPath: "README.md", Content: []byte("# x\n"), Ext: ".md"}}
+	for i := range 48 {
+		files = append(files, FileInfo{RelPath: fmt.Sprintf("pkg/f%02d.go", i), Content: []byte("package f\n"), Ext: ".go"})
+	}
+	files = append(files, FileInfo{RelPath: "empty.txt", Ext: ".txt"})
+	enc := tokenizer.NewHeuristic()
+
+	out, kept, omitted, err := ProcessWithBudget(NewMarkdownProcessor(), files, Options{HeadingLevel: 1}, Budget{MaxTokens: 300}, enc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := enc.Count(out); n > 300 {
+		t.Errorf("expected output within 300 tokens, got %d", n)
+	}
+	if len(kept)+len(omitted) != len(files) {
+		t.Fatalf("expected every file kept or omitted, got %d and %d", len(kept), len(omitted))
+	}
+
+	output := string(out)
+	for _, want := range []string{"# README.md", "# empty.txt", "# pkg/f00.go", "# Omitted files", " more file(s)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q", want)
+		}
+	}
+	if strings.Contains(output, "No files found") {
+		t.Error("expected the files that fit to be kept")
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	enc := tokenizer.NewHeuristic()
+	file := FileInfo{RelPath: "a.txt", Content: bytes.Repeat([]byte("0123456789abcde\n"), 20)}
+
+	truncated, dropped := truncateToTokens(file, 40, enc)
+
+	if enc.Count(truncated.Content) > 40 {
+		t.Errorf("truncated content has %d tokens, expected at most 40", enc.Count(truncated.Content))
+	}
+	if dropped <= 0 {
+		t.Errorf("expected dropped tokens, got %d", dropped)
+	}
+	if !strings.HasSuffix(string(truncated.Content), " tokens]\n") {
+		t.Errorf("expected marker at end, got %q", truncated.Content)
+	}
+	if !bytes.HasPrefix(file.Content, bytes.Split(truncated.Content, []byte("..."))[0]) {
+		t.Error("truncated content should be a prefix of the original")
+	}
+}
+
+// paddedProcessor adds a note per file that the budget estimate knows
+// nothing about, so that the estimate is too low, and counts how often it
+// renders.
+type paddedProcessor struct {
+	Processor
+	calls int
+}
+
+func (p *paddedProcessor) Process(files []FileInfo, opts Options) ([]byte, error) {
+	p.calls++
+	out, err := p.Processor.Process(files, opts)
+	return append([]byte(strings.Repeat("note ", 12*len(files))), out...), err
+}
+
+func TestProcessWithBudget_Underestimate(t *testing.T) {
+	var files []FileInfo
+	for i := range 200 {
+		files = append(files, FileInfo{RelPath: fmt.Sprintf("f%03d.go", i), Content: []byte("package f\n"), Ext: ".go"})
+	}
+	enc := tokenizer.NewHeuristic()
+	proc := &paddedProcessor{Processor: NewMarkdownProcessor()}
+
+	out, _, omitted, err := ProcessWithBudget(proc, files, Options{HeadingLevel: 1}, Budget{MaxTokens: 3000}, enc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := enc.Count(out); n > 3000 {
+		t.Errorf("expected output within 3000 tokens, got %d", n)
+	}
+	if len(omitted) == 0 || len(omitted) == len(files) {
+		t.Fatalf("expected some files to be dropped, got %d of %d", len(omitted), len(files))
+	}
+	if proc.calls > 3 {
+		t.Errorf("expected the overshoot to be dropped in one step, rendered %d times", proc.calls)
+	}
+}
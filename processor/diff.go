@@ -0,0 +1,230 @@
+package processor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// DiffProcessor renders each file as a unified diff against the version in
+// Options.Base, using the same headings and fences as MarkdownProcessor.
+// Files without changes are left out. As in git, a change to or from binary
+// content is shown as a "Binary files differ" line rather than hunks, and
+// under BinarySkip it is left out like the binary files themselves.
+type DiffProcessor struct{}
+
+func NewDiffProcessor() *DiffProcessor {
+	return &DiffProcessor{}
+}
+
+func (d *DiffProcessor) Name() string {
+	return "diff"
+}
+
+func (d *DiffProcessor) FileExtension() string {
+	return ".diff.md"
+}
+
+func (d *DiffProcessor) Process(files []FileInfo, opts Options) ([]byte, error) {
+	var out bytes.Buffer
+
+	headingLevel := clamp(opts.HeadingLevel, 1, 6)
+	heading := strings.Repeat("#", headingLevel)
+
+	writeMarkdownPart(&out, heading, opts)
+
+	written := 0
+	for _, file := range files {
+		base, hasBase, err := readBase(opts.Base, file.RelPath)
+		if err != nil {
+			return nil, err
+		}
+		if hasBase && sameContent(base, file) {
+			continue
+		}
+		binary := file.Binary || hasBase && isBinary(base)
+		if binary && opts.BinaryPolicy == BinarySkip {
+			continue
+		}
+
+		from := &diffFile{path: file.RelPath, content: base}
+		if !hasBase {
+			from = nil
+		}
+		if err := writeFileDiff(&out, heading, from, &diffFile{path: file.RelPath, content: file.Content}, binary, opts); err != nil {
+			return nil, err
+		}
+
+		if opts.DiffFull {
+			fence := fenceFor(file.Content)
+			fmt.Fprintf(&out, "Full file:\n\n%s%s\n", fence, fileLanguage(file))
+			out.Write(file.Content)
+			if len(file.Content) > 0 && file.Content[len(file.Content)-1] != '\n' {
+				out.WriteByte('\n')
+			}
+			fmt.Fprintf(&out, "%s\n\n", fence)
+		}
+		written++
+	}
+
+	for _, relPath := range opts.Deleted {
+		base, hasBase, err := readBase(opts.Base, relPath)
+		if err != nil {
+			return nil, err
+		}
+		if !hasBase {
+			continue
+		}
+		binary := isBinary(base)
+		if binary && opts.BinaryPolicy == BinarySkip {
+			continue
+		}
+		if err := writeFileDiff(&out, heading, &diffFile{path: relPath, content: base}, nil, binary, opts); err != nil {
+			return nil, err
+		}
+		written++
+	}
+
+	if written == 0 {
+		fmt.Fprintln(&out, "_No changes found._")
+	}
+
+	writeMarkdownOmitted(&out, heading, opts)
+
+	return out.Bytes(), nil
+}
+
+func writeFileDiff(out *bytes.Buffer, heading string, from, to *diffFile, binary bool, opts Options) error {
+	path := to
+	status := "modified"
+	switch {
+	case from == nil:
+		status = "added"
+	case to == nil:
+		path = from
+		status = "deleted"
+	}
+
+	var patch bytes.Buffer
+	if err := fdiff.NewUnifiedEncoder(&patch, max(opts.DiffContext, 0)).Encode(newFilePatch(from, to, binary)); err != nil {
+		return fmt.Errorf("diffing %s: %w", path.path, err)
+	}
+
+	fmt.Fprintf(out, "%s %s\n\n", heading, filepath.ToSlash(path.path))
+	fmt.Fprintf(out, "_%s_\n\n", status)
+	fence := fenceFor(patch.Bytes())
+	fmt.Fprintf(out, "%sdiff\n", fence)
+	out.Write(patch.Bytes())
+	if patch.Len() > 0 && patch.Bytes()[patch.Len()-1] != '\n' {
+		out.WriteByte('\n')
+	}
+	fmt.Fprintf(out, "%s\n\n", fence)
+	return nil
+}
+
+// sameContent reports whether file is unchanged from base. A binary file's
+// content has been replaced, so its hash is compared instead.
+func sameContent(base []byte, file FileInfo) bool {
+	if file.Binary {
+		sum := sha256.Sum256(base)
+		return hex.EncodeToString(sum[:]) == file.SHA256
+	}
+	return bytes.Equal(base, file.Content)
+}
+
+func isBinary(content []byte) bool {
+	binary, _ := DetectBinary(content)
+	return binary
+}
+
+// readBase returns the base version of relPath, and false if the file did
+// not exist in the base.
+func readBase(base fs.FS, relPath string) ([]byte, bool, error) {
+	if base == nil {
+		return nil, false, nil
+	}
+	content, err := fs.ReadFile(base, filepath.ToSlash(relPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("reading base version of %s: %w", relPath, err)
+	}
+	return content, true, nil
+}
+
+// The types below adapt two file versions to go-git's diff.Patch so that
+// its unified encoder can format them.
+
+type diffFile struct {
+	path    string
+	content []byte
+}
+
+func (f *diffFile) Hash() plumbing.Hash {
+	return plumbing.ComputeHash(plumbing.BlobObject, f.content)
+}
+
+func (f *diffFile) Mode() filemode.FileMode { return filemode.Regular }
+func (f *diffFile) Path() string            { return filepath.ToSlash(f.path) }
+
+type filePatch struct {
+	from, to fdiff.File
+	binary   bool
+	chunks   []fdiff.Chunk
+}
+
+// newFilePatch diffs from and to line by line, or only marks the patch
+// binary when binary is set.
+func newFilePatch(from, to *diffFile, binary bool) *filePatch {
+	p := &filePatch{binary: binary}
+	var src, dst string
+	if from != nil {
+		p.from = from
+		src = string(from.content)
+	}
+	if to != nil {
+		p.to = to
+		dst = string(to.content)
+	}
+	if binary {
+		return p
+	}
+
+	for _, d := range diff.Do(src, dst) {
+		op := fdiff.Equal
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			op = fdiff.Add
+		case diffmatchpatch.DiffDelete:
+			op = fdiff.Delete
+		}
+		p.chunks = append(p.chunks, chunkOp{content: d.Text, op: op})
+	}
+	return p
+}
+
+func (p *filePatch) IsBinary() bool                 { return p.binary }
+func (p *filePatch) Files() (from, to fdiff.File)   { return p.from, p.to }
+func (p *filePatch) Chunks() []fdiff.Chunk          { return p.chunks }
+func (p *filePatch) FilePatches() []fdiff.FilePatch { return []fdiff.FilePatch{p} }
+func (p *filePatch) Message() string                { return "" }
+
+type chunkOp struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c chunkOp) Content() string       { return c.content }
+func (c chunkOp) Type() fdiff.Operation { return c.op }
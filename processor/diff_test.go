@@ -0,0 +1,129 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDiffProcessor(t *testing.T) {
+	proc := NewDiffProcessor()
+
+	t.Run("Name and extension", func(t *testing.T) {
+		if proc.Name() != "diff" {
+			t.Errorf("expected name 'diff', got '%s'", proc.Name())
+		}
+		if proc.FileExtension() != ".diff.md" {
+			t.Errorf("expected extension '.diff.md', got '%s'", proc.FileExtension())
+		}
+	})
+
+	base := fstest.MapFS{
+		"main.go":    {Data: []byte("package main\n\nfunc a() {}\nfunc b() {}\nfunc c() {}\nfunc d() {}\nfunc e() {}\n")},
+		"same.go":    {Data: []byte("package main\n")},
+		"removed.go": {Data: []byte("package old\n")},
+	}
+	files := []FileInfo{
+		{RelPath: "main.go", Ext: ".go", Content: []byte("package main\n\nfunc a() {}\nfunc b() {}\nfunc c() {}\nfunc d() {}\nfunc e2() {}\n")},
+		{RelPath: "new.go", Ext: ".go", Content: []byte("package main\n")},
+		{RelPath: "same.go", Ext: ".go", Content: []byte("package main\n")},
+	}
+
+	t.Run("Modified, added and deleted files", func(t *testing.T) {
+		opts := Options{HeadingLevel: 2, Base: base, DiffContext: 1, Deleted: []string{"removed.go"}}
+		result, err := proc.Process(files, opts)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		output := string(result)
+
+		for _, want := range []string{
+			"## main.go\n\n_modified_\n\n```diff\n",
+			"@@ -6,2 +6,2 @@ func c() {}\n func d() {}\n-func e() {}\n+func e2() {}\n```\n",
+			"## new.go\n\n_added_\n",
+			"--- /dev/null\n+++ b/new.go\n",
+			"## removed.go\n\n_deleted_\n",
+			"-package old\n",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected %q in output:\n%s", want, output)
+			}
+		}
+		if strings.Contains(output, "same.go") {
+			t.Error("unchanged file should be left out")
+		}
+		if strings.Contains(output, "func b() {}") {
+			t.Error("expected context to be limited to one line")
+		}
+	})
+
+	t.Run("Full file", func(t *testing.T) {
+		opts := Options{HeadingLevel: 1, Base: base, DiffContext: 3, DiffFull: true}
+		result, err := proc.Process(files[:1], opts)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !strings.Contains(string(result), "Full file:\n\n```go\npackage main\n") {
+			t.Errorf("expected full file after the diff, got:\n%s", result)
+		}
+	})
+
+	t.Run("No changes", func(t *testing.T) {
+		result, err := proc.Process(files[2:], Options{Base: base})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !strings.Contains(string(result), "No changes found") {
+			t.Errorf("expected 'No changes found' message, got:\n%s", result)
+		}
+	})
+
+	t.Run("Binary files", func(t *testing.T) {
+		png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+		base := fstest.MapFS{
+			"logo.png": {Data: png},
+			"same.png": {Data: png},
+			"old.bin":  {Data: []byte("\x00\x01\x02")},
+		}
+		var current []FileInfo
+		for _, file := range []FileInfo{
+			{RelPath: "logo.png", Content: append(png[:len(png):len(png)], 'x')},
+			{RelPath: "same.png", Content: png},
+		} {
+			file, _, err := HandleBinary(file, BinaryPlaceholder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			current = append(current, file)
+		}
+
+		opts := Options{Base: base, BinaryPolicy: BinaryPlaceholder, Deleted: []string{"old.bin"}}
+		result, err := proc.Process(current, opts)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		output := string(result)
+		for _, want := range []string{
+			"Binary files a/logo.png and b/logo.png differ\n",
+			"Binary files a/old.bin and /dev/null differ\n",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected %q in output:\n%s", want, output)
+			}
+		}
+		for _, unwanted := range []string{"same.png", "@@", "[binary file"} {
+			if strings.Contains(output, unwanted) {
+				t.Errorf("unexpected %q in output:\n%s", unwanted, output)
+			}
+		}
+
+		opts.BinaryPolicy = BinarySkip
+		result, err = proc.Process(nil, opts)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if strings.Contains(string(result), "old.bin") {
+			t.Errorf("expected binary deletion to be skipped, got:\n%s", result)
+		}
+	})
+}
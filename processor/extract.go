@@ -0,0 +1,54 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type Extractor interface {
+	Extract(data []byte) ([]FileInfo, error)
+}
+
+func (r *Registry) GetExtractor(name string) (Extractor, error) {
+	p, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := p.(Extractor)
+	if !ok {
+		return nil, fmt.Errorf("processor %s does not support extraction", name)
+	}
+	return e, nil
+}
+
+// DetectFormat guesses which processor produced a bundle, first from the
+// bundle's file extension and then from its leading content.
+func (r *Registry) DetectFormat(bundlePath string, data []byte) string {
+	ext := strings.ToLower(filepath.Ext(bundlePath))
+	for _, name := range r.List() {
+		if p := r.processors[name]; ext != "" && p.FileExtension() == ext {
+			return name
+		}
+	}
+
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return "json"
+	case bytes.HasPrefix(trimmed, []byte("<documents>")), bytes.HasPrefix(trimmed, []byte("<?xml")):
+		return "xml"
+	default:
+		return "markdown"
+	}
+}
+
+func extractedFile(relPath string, content []byte) FileInfo {
+	relPath = filepath.FromSlash(strings.TrimSpace(relPath))
+	return FileInfo{
+		RelPath: relPath,
+		Content: content,
+		Ext:     filepath.Ext(relPath),
+	}
+}
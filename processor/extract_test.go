@@ -0,0 +1,251 @@
+package processor
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func goldenFiles() []FileInfo {
+	return []FileInfo{
+		{RelPath: "main.go", Content: []byte("package main\n\nfunc main() {}\n"), Ext: ".go"},
+		{RelPath: filepath.Join("docs", "README.md"), Content: []byte("# Docs\n\n```go\nfmt.Println(\"hi\")\n```\n\n## Usage\n"), Ext: ".md"},
+		{RelPath: filepath.Join("web", "index.html"), Content: []byte("<p>a &amp; b</p>\n<!-- ]]> -->\n"), Ext: ".html"},
+		{RelPath: "empty.txt", Content: []byte{}, Ext: ".txt"},
+		{RelPath: filepath.Join("scripts", "notes.txt"), Content: []byte("~~~\nnot a fence opener\n~~~\n"), Ext: ".txt"},
+		{RelPath: "VERSION", Content: []byte("1.2.3"), Ext: ""},
+		{RelPath: "run.bat", Content: []byte("@echo off\r\necho <hi>\r\n"), Ext: ".bat"},
+	}
+}
+
+func TestExtract_Golden(t *testing.T) {
+	opts := Options{
+		BaseDir:      "/project",
+		HeadingLevel: 2,
+		Extensions:   []string{".go", ".html", ".md", ".txt"},
+		GeneratedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	tests := []struct {
+		golden string
+		proc   interface {
+			Processor
+			Extractor
+		}
+	}{
+		{"bundle.md", NewMarkdownProcessor()},
+		{"bundle.json", NewJSONProcessor()},
+		{"bundle.xml", NewXMLProcessor()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			files := goldenFiles()
+			goldenPath := filepath.Join("testdata", "golden", tt.golden)
+
+			output, err := tt.proc.Process(files, opts)
+			if err != nil {
+				t.Fatalf("process failed: %v", err)
+			}
+
+			if *update {
+				if err := os.WriteFile(goldenPath, output, 0644); err != nil {
+					t.Fatalf("failed to update golden file: %v", err)
+				}
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v", err)
+			}
+			if !bytes.Equal(output, golden) {
+				t.Errorf("output does not match %s (run with -update to refresh)\n%s", goldenPath, output)
+			}
+
+			extracted, err := tt.proc.Extract(golden)
+			if err != nil {
+				t.Fatalf("extract failed: %v", err)
+			}
+			assertSameFiles(t, files, extracted)
+		})
+	}
+}
+
+func TestExtract_Tree(t *testing.T) {
+	// A file named like the tree's heading, fenced as text, must not be
+	// mistaken for the tree.
+	files := append(goldenFiles(), FileInfo{RelPath: "Directory tree", Content: []byte("notes\n"), Language: "text"})
+	for i := range files {
+		files[i].Size = int64(len(files[i].Content))
+	}
+	opts := Options{BaseDir: "/project", HeadingLevel: 2, Tree: true, TreeAnnotate: TreeSize}
+
+	tests := []struct {
+		name string
+		proc interface {
+			Processor
+			Extractor
+		}
+		want string
+	}{
+		{"markdown", NewMarkdownProcessor(), "## Directory tree\n\n<!-- amalgo:tree -->\n```text\n.\n├── main.go (29 B)\n├── docs/\n│   └── README.md (46 B)\n"},
+		{"json", NewJSONProcessor(), `"tree": {
+    "name": ".",
+    "type": "dir",
+    "children": [
+      {
+        "name": "main.go",
+        "type": "file",
+        "size": 29
+      },`},
+		{"xml", NewXMLProcessor(), "<directory_tree>\n<file name=\"main.go\" size=\"29\"/>\n<directory name=\"docs\">\n<file name=\"README.md\" size=\"46\"/>\n</directory>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := tt.proc.Process(files, opts)
+			if err != nil {
+				t.Fatalf("process failed: %v", err)
+			}
+			if !strings.Contains(string(output), tt.want) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.want, output)
+			}
+
+			extracted, err := tt.proc.Extract(output)
+			if err != nil {
+				t.Fatalf("extract failed: %v", err)
+			}
+			assertSameFiles(t, files, extracted)
+		})
+	}
+}
+
+func TestMarkdownExtract(t *testing.T) {
+	proc := NewMarkdownProcessor()
+
+	t.Run("CRLF content is preserved", func(t *testing.T) {
+		files := []FileInfo{
+			{RelPath: "run.bat", Content: []byte("@echo off\r\necho hi\r\n"), Ext: ".bat"},
+		}
+
+		output, err := proc.Process(files, Options{HeadingLevel: 1})
+		if err != nil {
+			t.Fatalf("process failed: %v", err)
+		}
+
+		extracted, err := proc.Extract(output)
+		if err != nil {
+			t.Fatalf("extract failed: %v", err)
+		}
+		assertSameFiles(t, files, extracted)
+	})
+
+	t.Run("Missing final newline is kept", func(t *testing.T) {
+		files := []FileInfo{
+			{RelPath: "a.go", Content: []byte("package a"), Ext: ".go"},
+			{RelPath: "b.go", Content: []byte("package b\n"), Ext: ".go"},
+		}
+
+		output, err := proc.Process(files, Options{HeadingLevel: 1})
+		if err != nil {
+			t.Fatalf("process failed: %v", err)
+		}
+
+		extracted, err := proc.Extract(output)
+		if err != nil {
+			t.Fatalf("extract failed: %v", err)
+		}
+		assertSameFiles(t, files, extracted)
+	})
+
+	t.Run("Fences without headings are skipped", func(t *testing.T) {
+		input := "Some intro text\n\n```text\nnot a file\n```\n\n# a.go\n```go\npackage a\n```\n"
+
+		extracted, err := proc.Extract([]byte(input))
+		if err != nil {
+			t.Fatalf("extract failed: %v", err)
+		}
+		if len(extracted) != 1 || extracted[0].RelPath != "a.go" {
+			t.Fatalf("expected only a.go, got %+v", extracted)
+		}
+	})
+
+	t.Run("Unterminated fence", func(t *testing.T) {
+		_, err := proc.Extract([]byte("# a.go\n```go\npackage a\n"))
+		if err == nil {
+			t.Error("expected error for unterminated fence")
+		}
+	})
+
+	t.Run("No files message", func(t *testing.T) {
+		extracted, err := proc.Extract([]byte("_No files found._\n"))
+		if err != nil {
+			t.Fatalf("extract failed: %v", err)
+		}
+		if len(extracted) != 0 {
+			t.Errorf("expected no files, got %d", len(extracted))
+		}
+	})
+}
+
+func TestRegistry_DetectFormat(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(NewMarkdownProcessor())
+	reg.Register(NewJSONProcessor())
+	reg.Register(NewXMLProcessor())
+
+	tests := []struct {
+		name     string
+		path     string
+		data     string
+		expected string
+	}{
+		{"markdown extension", "concat.md", "{", "markdown"},
+		{"json extension", "concat.json", "# a", "json"},
+		{"xml extension", "out.XML", "", "xml"},
+		{"json content", "-", "  {\"files\": []}", "json"},
+		{"xml content", "bundle.txt", "<documents>\n</documents>", "xml"},
+		{"markdown fallback", "-", "# main.go\n", "markdown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := reg.DetectFormat(tt.path, []byte(tt.data))
+			if result != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("processor without extractor", func(t *testing.T) {
+		reg.Register(&mockProcessor{name: "mock"})
+		if _, err := reg.GetExtractor("mock"); err == nil {
+			t.Error("expected error for processor without extraction support")
+		}
+	})
+}
+
+func assertSameFiles(t *testing.T, expected, actual []FileInfo) {
+	t.Helper()
+
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d files, got %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		if actual[i].RelPath != expected[i].RelPath {
+			t.Errorf("file %d: expected path '%s', got '%s'", i, expected[i].RelPath, actual[i].RelPath)
+		}
+		if actual[i].Ext != expected[i].Ext {
+			t.Errorf("file %d: expected ext '%s', got '%s'", i, expected[i].Ext, actual[i].Ext)
+		}
+		if !bytes.Equal(actual[i].Content, expected[i].Content) {
+			t.Errorf("file %d (%s): content mismatch\nexpected: %q\ngot:      %q", i, expected[i].RelPath, expected[i].Content, actual[i].Content)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+type JSONProcessor struct{}
+
+func NewJSONProcessor() *JSONProcessor {
+	return &JSONProcessor{}
+}
+
+func (j *JSONProcessor) Name() string {
+	return "json"
+}
+
+func (j *JSONProcessor) FileExtension() string {
+	return ".json"
+}
+
+type jsonDocument struct {
+	Part        *jsonPart  `json:"part,omitempty"`
+	BaseDir     string     `json:"base_dir"`
+	Extensions  []string   `json:"extensions"`
+	FileCount   int        `json:"file_count"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Tree        *jsonTree  `json:"tree,omitempty"`
+	Files       []jsonFile `json:"files"`
+	Deleted     []string   `json:"deleted,omitempty"`
+	Omitted     []jsonOmit `json:"omitted,omitempty"`
+	Unlisted    int        `json:"omitted_unlisted,omitempty"`
+}
+
+type jsonPart struct {
+	Index int            `json:"index"`
+	Total int            `json:"total"`
+	Files []jsonPartFile `json:"files"`
+}
+
+type jsonPartFile struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+}
+
+type jsonOmit struct {
+	Path      string `json:"path"`
+	Tokens    int    `json:"tokens"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// jsonTree is a node of the directory tree. Size and Lines are only given
+// for files, when the tree is annotated with them.
+type jsonTree struct {
+	Name     string     `json:"name"`
+	Type     string     `json:"type"`
+	Size     *int64     `json:"size,omitempty"`
+	Lines    *int       `json:"lines,omitempty"`
+	Children []jsonTree `json:"children,omitempty"`
+}
+
+type jsonFile struct {
+	Path     string    `json:"path"`
+	Ext      string    `json:"ext"`
+	Language string    `json:"language"`
+	Size     int       `json:"size"`
+	Lines    int       `json:"lines"`
+	Content  string    `json:"content"`
+	Meta     *jsonMeta `json:"meta,omitempty"`
+}
+
+// jsonMeta describes the file as it was read, before any truncation.
+type jsonMeta struct {
+	Size     int64      `json:"size"`
+	Lines    int        `json:"lines"`
+	Modified *time.Time `json:"modified,omitempty"`
+	Mode     string     `json:"mode,omitempty"`
+	SHA256   string     `json:"sha256"`
+}
+
+func (j *JSONProcessor) Process(files []FileInfo, opts Options) ([]byte, error) {
+	doc := jsonDocument{
+		BaseDir:     filepath.ToSlash(opts.BaseDir),
+		Extensions:  opts.Extensions,
+		FileCount:   len(files),
+		GeneratedAt: generatedAt(opts),
+		Files:       make([]jsonFile, 0, len(files)),
+	}
+	if doc.Extensions == nil {
+		doc.Extensions = []string{}
+	}
+
+	for _, file := range files {
+		f := jsonFile{
+			Path:     filepath.ToSlash(file.RelPath),
+			Ext:      file.Ext,
+			Language: fileLanguage(file),
+			Size:     len(file.Content),
+			Lines:    countLines(file.Content),
+			Content:  string(file.Content),
+		}
+		if hasMeta(file, opts) {
+			f.Meta = &jsonMeta{Size: file.Size, Lines: file.Lines, SHA256: file.SHA256}
+			if !file.ModTime.IsZero() {
+				f.Meta.Modified = &file.ModTime
+			}
+			if file.Mode != 0 {
+				f.Meta.Mode = file.Mode.String()
+			}
+		}
+		doc.Files = append(doc.Files, f)
+	}
+
+	if opts.Tree {
+		tree, err := NewTree(files, opts.TreeAnnotate)
+		if err != nil {
+			return nil, err
+		}
+		root := newJSONTree(tree.Root, tree.Annotate)
+		doc.Tree = &root
+	}
+
+	if opts.Part != nil {
+		doc.Part = &jsonPart{Index: opts.Part.Index, Total: opts.Part.Total, Files: []jsonPartFile{}}
+		for _, f := range opts.Part.Files {
+			doc.Part.Files = append(doc.Part.Files, jsonPartFile{
+				Path:      filepath.ToSlash(f.RelPath),
+				StartLine: f.StartLine,
+				EndLine:   f.EndLine,
+			})
+		}
+	}
+
+	for _, path := range opts.Deleted {
+		doc.Deleted = append(doc.Deleted, filepath.ToSlash(path))
+	}
+
+	doc.Unlisted = opts.Unlisted
+	for _, o := range opts.Omitted {
+		doc.Omitted = append(doc.Omitted, jsonOmit{
+			Path:      filepath.ToSlash(o.RelPath),
+			Tokens:    o.Tokens,
+			Truncated: o.Truncated,
+		})
+	}
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func newJSONTree(node *TreeNode, annotate string) jsonTree {
+	if !node.Dir {
+		t := jsonTree{Name: node.Name, Type: "file"}
+		switch annotate {
+		case TreeSize:
+			t.Size = &node.Size
+		case TreeLines:
+			t.Lines = &node.Lines
+		}
+		return t
+	}
+
+	t := jsonTree{Name: node.Name, Type: "dir", Children: []jsonTree{}}
+	for _, c := range node.Children {
+		t.Children = append(t.Children, newJSONTree(c, annotate))
+	}
+	return t
+}
+
+func (j *JSONProcessor) Extract(data []byte) ([]FileInfo, error) {
+	var doc jsonDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing json bundle: %w", err)
+	}
+
+	files := make([]FileInfo, 0, len(doc.Files))
+	for _, f := range doc.Files {
+		files = append(files, extractedFile(f.Path, []byte(f.Content)))
+	}
+	return files, nil
+}
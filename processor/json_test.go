@@ -0,0 +1,166 @@
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestJSONProcessor(t *testing.T) {
+	proc := NewJSONProcessor()
+
+	t.Run("Name and extension", func(t *testing.T) {
+		if proc.Name() != "json" {
+			t.Errorf("expected name 'json', got '%s'", proc.Name())
+		}
+		if proc.FileExtension() != ".json" {
+			t.Errorf("expected extension '.json', got '%s'", proc.FileExtension())
+		}
+	})
+
+	t.Run("Empty files", func(t *testing.T) {
+		result, err := proc.Process([]FileInfo{}, Options{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		var doc jsonDocument
+		if err := json.Unmarshal(result, &doc); err != nil {
+			t.Fatalf("output is not valid JSON: %v", err)
+		}
+		if doc.FileCount != 0 || doc.Files == nil || len(doc.Files) != 0 {
+			t.Errorf("expected empty files array, got %+v", doc)
+		}
+	})
+
+	t.Run("Metadata and files", func(t *testing.T) {
+		generated := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		files := []FileInfo{
+			{
+				Path:    "/project/main.go",
+				RelPath: "main.go",
+				Content: []byte("package main\n\nfunc main() {}\n"),
+				Ext:     ".go",
+			},
+			{
+				RelPath: "cmd/root.go",
+				Content: []byte("package cmd"),
+				Ext:     ".go",
+			},
+		}
+
+		opts := Options{
+			BaseDir:     "/project",
+			Extensions:  []string{".go"},
+			GeneratedAt: generated,
+		}
+		result, err := proc.Process(files, opts)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		var doc jsonDocument
+		if err := json.Unmarshal(result, &doc); err != nil {
+			t.Fatalf("output is not valid JSON: %v", err)
+		}
+
+		if doc.BaseDir != "/project" {
+			t.Errorf("expected base_dir '/project', got '%s'", doc.BaseDir)
+		}
+		if len(doc.Extensions) != 1 || doc.Extensions[0] != ".go" {
+			t.Errorf("unexpected extensions: %v", doc.Extensions)
+		}
+		if doc.FileCount != 2 {
+			t.Errorf("expected file_count 2, got %d", doc.FileCount)
+		}
+		if !doc.GeneratedAt.Equal(generated) {
+			t.Errorf("expected generated_at %v, got %v", generated, doc.GeneratedAt)
+		}
+
+		first := doc.Files[0]
+		if first.Path != "main.go" || first.Language != "go" || first.Ext != ".go" {
+			t.Errorf("unexpected first file: %+v", first)
+		}
+		if first.Size != 29 {
+			t.Errorf("expected size 29, got %d", first.Size)
+		}
+		if first.Lines != 3 {
+			t.Errorf("expected 3 lines, got %d", first.Lines)
+		}
+		if first.Content != "package main\n\nfunc main() {}\n" {
+			t.Errorf("content mismatch: %q", first.Content)
+		}
+
+		if doc.Files[1].Path != "cmd/root.go" {
+			t.Errorf("expected slash-separated path, got '%s'", doc.Files[1].Path)
+		}
+		if doc.Files[1].Lines != 1 {
+			t.Errorf("expected 1 line for content without newline, got %d", doc.Files[1].Lines)
+		}
+	})
+
+	t.Run("Content is not HTML escaped", func(t *testing.T) {
+		files := []FileInfo{
+			{RelPath: "index.html", Content: []byte("<a>&</a>"), Ext: ".html"},
+		}
+
+		result, err := proc.Process(files, Options{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		var doc jsonDocument
+		if err := json.Unmarshal(result, &doc); err != nil {
+			t.Fatalf("output is not valid JSON: %v", err)
+		}
+		if doc.Files[0].Content != "<a>&</a>" {
+			t.Errorf("content mismatch: %q", doc.Files[0].Content)
+		}
+	})
+}
+
+func TestJSONProcessor_Omitted(t *testing.T) {
+	opts := Options{
+		Omitted: []OmittedFile{
+			{RelPath: "big.go", Tokens: 500},
+			{RelPath: "half.go", Tokens: 20, Truncated: true},
+		},
+	}
+
+	result, err := NewJSONProcessor().Process(nil, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var doc jsonDocument
+	if err := json.Unmarshal(result, &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(doc.Omitted) != 2 || doc.Omitted[0].Path != "big.go" || !doc.Omitted[1].Truncated {
+		t.Errorf("unexpected omitted list: %+v", doc.Omitted)
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected int
+	}{
+		{"empty", "", 0},
+		{"single line no newline", "a", 1},
+		{"single line", "a\n", 1},
+		{"multiple lines", "a\nb\nc\n", 3},
+		{"trailing content", "a\nb", 2},
+		{"blank lines", "\n\n", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := countLines([]byte(tt.content))
+			if result != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
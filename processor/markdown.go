@@ -1,10 +1,18 @@
 package processor
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
+	"iter"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"unicode"
+
+	"amalgo/lang"
 )
 
 type MarkdownProcessor struct{}
@@ -23,73 +31,266 @@ func (m *MarkdownProcessor) FileExtension() string {
 
 func (m *MarkdownProcessor) Process(files []FileInfo, opts Options) ([]byte, error) {
 	var out bytes.Buffer
-
-	if len(files) == 0 {
-		fmt.Fprintln(&out, "_No files found._")
-		return out.Bytes(), nil
+	if err := m.ProcessTo(&out, slices.Values(files), opts); err != nil {
+		return nil, err
 	}
+	return out.Bytes(), nil
+}
+
+// ProcessTo writes the bundle to w one file at a time, so only the file
+// being written needs to be held in memory. A table of contents or a
+// directory tree needs every file up front, so with opts.TOC or opts.Tree
+// the files are collected first.
+func (m *MarkdownProcessor) ProcessTo(w io.Writer, files iter.Seq[FileInfo], opts Options) error {
+	out := bufio.NewWriter(w)
 
 	headingLevel := clamp(opts.HeadingLevel, 1, 6)
 	heading := strings.Repeat("#", headingLevel)
 
-	for _, file := range files {
+	writeMarkdownPart(out, heading, opts)
+	if opts.TOC || opts.Tree {
+		collected := slices.Collect(files)
+		if opts.TOC {
+			writeMarkdownTOC(out, heading, collected, opts)
+		}
+		if err := writeMarkdownTree(out, heading, collected, opts); err != nil {
+			return err
+		}
+		files = slices.Values(collected)
+	}
+
+	written := 0
+	for file := range files {
 		relPath := filepath.ToSlash(file.RelPath)
 
-		fmt.Fprintf(&out, "%s %s\n", heading, relPath)
+		fmt.Fprintf(out, "%s %s\n", heading, relPath)
+		if hasMeta(file, opts) {
+			fmt.Fprintf(out, "%s\n\n", metaLine(file))
+		}
 
-		lang := inferLanguage(file.Ext)
-		fmt.Fprintf(&out, "```%s\n", lang)
+		lang := fileLanguage(file)
+		fence := fenceFor(file.Content)
+		fmt.Fprintf(out, "%s%s\n", fence, lang)
 
 		out.Write(file.Content)
 
-		if len(file.Content) == 0 || file.Content[len(file.Content)-1] != '\n' {
+		noEOL := len(file.Content) > 0 && file.Content[len(file.Content)-1] != '\n'
+		if noEOL {
 			out.WriteByte('\n')
 		}
 
-		out.WriteString("```\n\n")
+		fmt.Fprintf(out, "%s\n", fence)
+		if noEOL {
+			fmt.Fprintf(out, "%s\n", noEOLMarker)
+		}
+		out.WriteByte('\n')
+		written++
 	}
 
-	return out.Bytes(), nil
+	if written == 0 {
+		fmt.Fprintln(out, "_No files found._")
+	}
+
+	writeMarkdownDeleted(out, heading, opts)
+	writeMarkdownOmitted(out, heading, opts)
+
+	return out.Flush()
 }
 
-func inferLanguage(ext string) string {
-	ext = strings.ToLower(ext)
-	if !strings.HasPrefix(ext, ".") {
-		ext = "." + ext
-	}
-
-	langMap := map[string]string{
-		".go":   "go",
-		".rs":   "rust",
-		".py":   "python",
-		".js":   "javascript",
-		".ts":   "typescript",
-		".java": "java",
-		".c":    "c",
-		".cpp":  "cpp",
-		".cs":   "csharp",
-		".rb":   "ruby",
-		".php":  "php",
-		".sh":   "bash",
-		".bash": "bash",
-		".zsh":  "bash",
-		".html": "html",
-		".css":  "css",
-		".scss": "scss",
-		".json": "json",
-		".yaml": "yaml",
-		".yml":  "yaml",
-		".xml":  "xml",
-		".toml": "toml",
-		".sql":  "sql",
-		".md":   "markdown",
-		".txt":  "text",
-	}
-
-	if lang, ok := langMap[ext]; ok {
-		return lang
+func writeMarkdownPart(out textWriter, heading string, opts Options) {
+	if opts.Part == nil {
+		return
+	}
+
+	fmt.Fprintf(out, "%s %s\n\n", heading, partHeading(opts.Part))
+	out.WriteString("Files in this part:\n\n")
+	for _, f := range opts.Part.Files {
+		if f.StartLine > 0 {
+			fmt.Fprintf(out, "- `%s` (lines %d-%d)\n", filepath.ToSlash(f.RelPath), f.StartLine, f.EndLine)
+		} else {
+			fmt.Fprintf(out, "- `%s`\n", filepath.ToSlash(f.RelPath))
+		}
+	}
+	out.WriteByte('\n')
+}
+
+func partHeading(part *PartInfo) string {
+	return fmt.Sprintf("Part %d of %d", part.Index, part.Total)
+}
+
+const tocHeading = "Table of contents"
+
+// writeMarkdownTOC lists files grouped by directory, in order of each
+// directory's first file, linking each to its heading. The headings above
+// the files are counted too, so that repeated anchors are numbered as
+// GitHub numbers them.
+func writeMarkdownTOC(out textWriter, heading string, files []FileInfo, opts Options) {
+	if len(files) == 0 {
+		return
+	}
+
+	var anchors slugger
+	if opts.Part != nil {
+		anchors.slug(partHeading(opts.Part))
+	}
+	anchors.slug(tocHeading)
+	if opts.Tree {
+		anchors.slug(treeHeading)
+	}
+
+	type entry struct{ name, anchor string }
+	var dirs []string
+	groups := make(map[string][]entry)
+	for _, file := range files {
+		relPath := filepath.ToSlash(file.RelPath)
+		dir, name := path.Split(relPath)
+		if _, ok := groups[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		groups[dir] = append(groups[dir], entry{name, anchors.slug(relPath)})
+	}
+
+	fmt.Fprintf(out, "%s %s\n\n", heading, tocHeading)
+	for _, dir := range dirs {
+		indent := ""
+		if dir != "" {
+			fmt.Fprintf(out, "- `%s`\n", dir)
+			indent = "  "
+		}
+		for _, e := range groups[dir] {
+			fmt.Fprintf(out, "%s- [`%s`](#%s)\n", indent, e.name, e.anchor)
+		}
+	}
+	out.WriteByte('\n')
+}
+
+// slugger makes GitHub-compatible heading anchors: lowercased, with
+// punctuation and symbols dropped and spaces turned into hyphens. A repeated
+// anchor is numbered, skipping any numbers already taken.
+type slugger struct {
+	seen map[string]int
+}
+
+func (s *slugger) slug(title string) string {
+	if s.seen == nil {
+		s.seen = make(map[string]int)
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == ' ':
+			b.WriteByte('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r):
+			b.WriteRune(r)
+		}
+	}
+
+	base := b.String()
+	slug := base
+	for {
+		if _, taken := s.seen[slug]; !taken {
+			break
+		}
+		s.seen[base]++
+		slug = fmt.Sprintf("%s-%d", base, s.seen[base])
+	}
+	s.seen[slug] = 0
+	return slug
+}
+
+const treeHeading = "Directory tree"
+
+// noEOLMarker follows the closing fence of a file whose content does not
+// end in a newline, since the fence needs one before it. Extract removes
+// the newline again when it sees the marker.
+const noEOLMarker = "<!-- amalgo:no-final-newline -->"
+
+// treeMarker is an HTML comment, invisible when rendered, written before
+// the directory tree's fence so that Extract can skip the tree whatever
+// its heading says.
+const treeMarker = "<!-- amalgo:tree -->"
+
+func writeMarkdownTree(out textWriter, heading string, files []FileInfo, opts Options) error {
+	if !opts.Tree {
+		return nil
 	}
+	t, err := NewTree(files, opts.TreeAnnotate)
+	if err != nil {
+		return err
+	}
+
+	tree := t.String()
+	fence := fenceFor([]byte(tree))
+	fmt.Fprintf(out, "%s %s\n\n%s\n%stext\n%s%s\n\n", heading, treeHeading, treeMarker, fence, tree, fence)
+	return nil
+}
+
+func writeMarkdownDeleted(out textWriter, heading string, opts Options) {
+	if len(opts.Deleted) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "%s Deleted files\n\n", heading)
+	for _, path := range opts.Deleted {
+		fmt.Fprintf(out, "- `%s`\n", filepath.ToSlash(path))
+	}
+	out.WriteByte('\n')
+}
 
+func writeMarkdownOmitted(out textWriter, heading string, opts Options) {
+	if len(opts.Omitted) == 0 && opts.Unlisted == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "%s Omitted files\n\n", heading)
+	if opts.MaxTokens > 0 {
+		fmt.Fprintf(out, "The following files were left out or truncated to stay within the %d token budget:\n\n", opts.MaxTokens)
+	}
+	for _, o := range opts.Omitted {
+		if o.Truncated {
+			fmt.Fprintf(out, "- `%s` (truncated, %d tokens omitted)\n", filepath.ToSlash(o.RelPath), o.Tokens)
+		} else {
+			fmt.Fprintf(out, "- `%s` (%d tokens)\n", filepath.ToSlash(o.RelPath), o.Tokens)
+		}
+	}
+	if opts.Unlisted > 0 {
+		fmt.Fprintf(out, "- and %d more file(s)\n", opts.Unlisted)
+	}
+	out.WriteByte('\n')
+}
+
+// fenceFor returns a backtick fence longer than any backtick run in content,
+// so that content containing fences of its own cannot close the block early.
+func fenceFor(content []byte) string {
+	longest, run := 0, 0
+	for _, b := range content {
+		if b == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// fileLanguage returns the fence language for file: that of its detected
+// language if it has one, or else judged by its name and extension.
+func fileLanguage(file FileInfo) string {
+	if l, ok := lang.Lookup(file.Language); ok {
+		return l.Fence
+	}
+	if l, ok := lang.ForFilename(filepath.Base(file.RelPath)); ok {
+		return l.Fence
+	}
+	return inferLanguage(file.Ext)
+}
+
+func inferLanguage(ext string) string {
+	if l, ok := lang.ForExtension(ext); ok {
+		return l.Fence
+	}
 	return ""
 }
 
@@ -102,3 +303,107 @@ func clamp(v, lo, hi int) int {
 	}
 	return v
 }
+
+// Extract parses a bundle produced by Process back into files. Each fenced
+// block is attributed to the closest heading above it; fenced blocks
+// without a heading are skipped. The extracted content ends in a newline
+// unless the block is followed by the marker Process writes for content
+// that has none.
+func (m *MarkdownProcessor) Extract(data []byte) ([]FileInfo, error) {
+	var (
+		files   []FileInfo
+		heading string
+		fence   string
+		inBlock bool
+		closed  bool
+		lines   []string
+	)
+
+	for _, line := range strings.Split(string(data), "\n") {
+		structural := strings.TrimRight(line, " \t\r")
+
+		if inBlock {
+			if isClosingFence(structural, fence) {
+				closed = false
+				if heading != "" {
+					content := strings.Join(lines, "\n")
+					if len(lines) > 0 {
+						content += "\n"
+					}
+					files = append(files, extractedFile(heading, []byte(content)))
+					closed = true
+				}
+				heading, inBlock, lines = "", false, nil
+				continue
+			}
+			lines = append(lines, line)
+			continue
+		}
+
+		if closed && structural == noEOLMarker {
+			last := &files[len(files)-1]
+			last.Content = bytes.TrimSuffix(last.Content, []byte("\n"))
+		}
+		closed = false
+
+		if title, ok := parseHeading(structural); ok {
+			heading = title
+			continue
+		}
+
+		// The block after the tree marker is the directory tree, not a
+		// file, and is skipped like any block without a heading.
+		if structural == treeMarker {
+			heading = ""
+			continue
+		}
+
+		if f := openingFence(structural); f != "" {
+			fence, inBlock, lines = f, true, nil
+		}
+	}
+
+	if inBlock {
+		return nil, fmt.Errorf("unterminated code fence for %q", heading)
+	}
+
+	return files, nil
+}
+
+func parseHeading(line string) (string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || line[level] != ' ' {
+		return "", false
+	}
+	title := strings.TrimSpace(line[level:])
+	return title, title != ""
+}
+
+func openingFence(line string) string {
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return line[:n]
+}
+
+func isClosingFence(line, fence string) bool {
+	if len(line) < len(fence) {
+		return false
+	}
+	for i := 0; i < len(line); i++ {
+		if line[i] != fence[0] {
+			return false
+		}
+	}
+	return true
+}
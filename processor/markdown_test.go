@@ -142,6 +142,67 @@ func TestMarkdownProcessor(t *testing.T) {
 	})
 }
 
+func TestMarkdownProcessor_TOC(t *testing.T) {
+	files := []FileInfo{
+		{RelPath: "main.go", Content: []byte("package main\n")},
+		{RelPath: "cmd/root.go", Content: []byte("package cmd\n")},
+		{RelPath: "cmd-root.go", Content: []byte("package main\n")},
+		{RelPath: "cmd/sub/x.go", Content: []byte("package sub\n")},
+		{RelPath: "cmd/util.go", Content: []byte("package cmd\n")},
+		{RelPath: "Table of contents", Content: []byte("notes\n")},
+	}
+	opts := Options{HeadingLevel: 3, TOC: true, Tree: true, TreeAnnotate: TreePlain}
+
+	result, err := NewMarkdownProcessor().Process(files, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := "### Table of contents\n\n" +
+		"- [`main.go`](#maingo)\n" +
+		"- [`cmd-root.go`](#cmd-rootgo)\n" +
+		"- [`Table of contents`](#table-of-contents-1)\n" +
+		"- `cmd/`\n" +
+		"  - [`root.go`](#cmdrootgo)\n" +
+		"  - [`util.go`](#cmdutilgo)\n" +
+		"- `cmd/sub/`\n" +
+		"  - [`x.go`](#cmdsubxgo)\n\n" +
+		"### Directory tree\n"
+	if !strings.HasPrefix(string(result), want) {
+		t.Errorf("expected output to start with:\n%s\ngot:\n%s", want, result)
+	}
+
+	extracted, err := NewMarkdownProcessor().Extract(result)
+	if err != nil {
+		t.Fatalf("extract failed: %v", err)
+	}
+	if len(extracted) != len(files) {
+		t.Errorf("expected %d files back, got %d", len(files), len(extracted))
+	}
+}
+
+func TestSlugger(t *testing.T) {
+	var s slugger
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Part 1 of 2", "part-1-of-2"},
+		{"src/main.go", "srcmaingo"},
+		{"docs/Getting Started.md", "docsgetting-startedmd"},
+		{"my_file-name.ts", "my_file-namets"},
+		{"Ünïcode/файл.go", "ünïcodeфайлgo"},
+		{"src/main.go", "srcmaingo-1"},
+		{"srcmaingo-1", "srcmaingo-1-1"},
+		{"src/main.go", "srcmaingo-2"},
+	}
+	for _, tt := range tests {
+		if got := s.slug(tt.title); got != tt.want {
+			t.Errorf("slug(%q): expected %q, got %q", tt.title, tt.want, got)
+		}
+	}
+}
+
 func TestInferLanguage(t *testing.T) {
 	tests := []struct {
 		ext      string
@@ -176,6 +237,33 @@ func TestInferLanguage(t *testing.T) {
 	}
 }
 
+func TestFileLanguage(t *testing.T) {
+	tests := []struct {
+		relPath  string
+		ext      string
+		expected string
+	}{
+		{"Makefile", "", "makefile"},
+		{"build/Dockerfile", "", "dockerfile"},
+		{"go.mod", ".mod", "go-mod"},
+		{"types.d.ts", ".ts", "typescript"},
+		{"app.test.js", ".js", "javascript"},
+		{"views/index.html.erb", ".erb", "erb"},
+		{"main.go", ".go", "go"},
+		{"LICENSE", "", ""},
+		{"", ".py", "python"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relPath, func(t *testing.T) {
+			result := fileLanguage(FileInfo{RelPath: tt.relPath, Ext: tt.ext})
+			if result != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestClamp(t *testing.T) {
 	tests := []struct {
 		name     string
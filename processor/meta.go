@@ -0,0 +1,26 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// hasMeta reports whether file's metadata should be shown: it was asked for
+// and the file was read.
+func hasMeta(file FileInfo, opts Options) bool {
+	return opts.Meta && file.Err == nil && file.SHA256 != ""
+}
+
+// metaLine summarises file's metadata on one line for the markdown output.
+func metaLine(file FileInfo) string {
+	parts := []string{fmt.Sprintf("%d bytes", file.Size), formatLines(file.Lines)}
+	if !file.ModTime.IsZero() {
+		parts = append(parts, "modified "+file.ModTime.Format(time.RFC3339))
+	}
+	if file.Mode != 0 {
+		parts = append(parts, "mode "+file.Mode.String())
+	}
+	parts = append(parts, "sha256 `"+file.SHA256+"`")
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMeta(t *testing.T) {
+	files := []FileInfo{
+		{
+			RelPath: "main.go",
+			Ext:     ".go",
+			Content: []byte("package main\n"),
+			Size:    13,
+			Lines:   1,
+			ModTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Mode:    0644,
+			SHA256:  "abc123",
+		},
+		{RelPath: "gone.go", Ext: ".go", Content: []byte("ERROR"), SHA256: "abc123", Err: fs.ErrNotExist},
+	}
+
+	tests := []struct {
+		proc Processor
+		want string
+	}{
+		{NewMarkdownProcessor(), "# main.go\n13 bytes, 1 line, modified 2024-01-02T03:04:05Z, mode -rw-r--r--, sha256 `abc123`\n\n```go\n"},
+		{NewJSONProcessor(), `"meta": {
+        "size": 13,
+        "lines": 1,
+        "modified": "2024-01-02T03:04:05Z",
+        "mode": "-rw-r--r--",
+        "sha256": "abc123"
+      }`},
+		{NewXMLProcessor(), "<source>main.go</source>\n<metadata size=\"13\" lines=\"1\" modified=\"2024-01-02T03:04:05Z\" mode=\"-rw-r--r--\" sha256=\"abc123\"/>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.proc.Name(), func(t *testing.T) {
+			with, err := tt.proc.Process(files, Options{HeadingLevel: 1, Meta: true})
+			if err != nil {
+				t.Fatalf("process failed: %v", err)
+			}
+			if !strings.Contains(string(with), tt.want) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.want, with)
+			}
+			if n := strings.Count(string(with), "abc123"); n != 1 {
+				t.Errorf("expected metadata for the readable file only, found it %d times", n)
+			}
+
+			without, err := tt.proc.Process(files, Options{HeadingLevel: 1})
+			if err != nil {
+				t.Fatalf("process failed: %v", err)
+			}
+			if strings.Contains(string(without), "abc123") {
+				t.Errorf("expected no metadata without Meta, got:\n%s", without)
+			}
+		})
+	}
+}
+
+func TestMetaLine(t *testing.T) {
+	file := FileInfo{Size: 0, Lines: 0, SHA256: "e3b0"}
+	if got, want := metaLine(file), "0 bytes, 0 lines, sha256 `e3b0`"; got != want {
+		t.Errorf("expected %q for a file without mod time or mode, got %q", want, got)
+	}
+}
@@ -1,9 +1,22 @@
 package processor
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/fs"
+	"iter"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"amalgo/lang"
 )
 
 type FileInfo struct {
@@ -11,12 +24,45 @@ type FileInfo struct {
 	RelPath string
 	Content []byte
 	Ext     string
+	// Err is set when the file could not be read. Content then holds an
+	// error message in its place so that the failure shows in the output.
+	Err error
+	// Language names the file's language in the lang catalogue when it was
+	// detected from a "#!" line, for files whose name does not tell.
+	Language string
+	// Binary is set when the content was binary and has been replaced by a
+	// placeholder or its base64 encoding under the binary policy.
+	Binary bool
+	// Size, Lines and SHA256 describe the content as it was read, before
+	// any truncation. SHA256, ModTime and Mode are only filled in when the
+	// files are loaded with meta, SHA256 also for Binary files, and ModTime
+	// and Mode are left zero when the file system does not record them.
+	Size    int64
+	Lines   int
+	ModTime time.Time
+	Mode    fs.FileMode
+	SHA256  string
 }
 
 type Options struct {
 	BaseDir       string
 	HeadingLevel  int
 	IncludeErrors bool
+	Extensions    []string
+	GeneratedAt   time.Time
+	Omitted       []OmittedFile
+	Unlisted      int
+	MaxTokens     int
+	Part          *PartInfo
+	Deleted       []string
+	Base          fs.FS
+	BinaryPolicy  string
+	DiffContext   int
+	DiffFull      bool
+	Tree          bool
+	TreeAnnotate  string
+	TOC           bool
+	Meta          bool
 }
 
 type Processor interface {
@@ -27,6 +73,37 @@ type Processor interface {
 	Process(files []FileInfo, opts Options) ([]byte, error)
 }
 
+// StreamProcessor is implemented by processors that can write their output
+// as files arrive rather than building it in memory first.
+type StreamProcessor interface {
+	Processor
+
+	ProcessTo(w io.Writer, files iter.Seq[FileInfo], opts Options) error
+}
+
+// ProcessTo writes p's output for files to w. Processors that do not
+// implement StreamProcessor are given the collected files and their output
+// is written in one piece.
+func ProcessTo(p Processor, w io.Writer, files iter.Seq[FileInfo], opts Options) error {
+	if sp, ok := p.(StreamProcessor); ok {
+		return sp.ProcessTo(w, files, opts)
+	}
+
+	content, err := p.Process(slices.Collect(files), opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// textWriter is satisfied by both bytes.Buffer and bufio.Writer.
+type textWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
 type Registry struct {
 	processors map[string]Processor
 }
@@ -54,35 +131,186 @@ func (r *Registry) List() []string {
 	for name := range r.processors {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
 
+// LoadFiles reads paths from disk as given, so they may lie outside
+// baseDir; RelPath is made relative to baseDir where possible.
 func LoadFiles(paths []string, baseDir string) ([]FileInfo, error) {
-	infos := make([]FileInfo, 0, len(paths))
-
-	for _, path := range paths {
-		content, err := os.ReadFile(path)
-		if err != nil {
-			infos = append(infos, FileInfo{
-				Path:    path,
-				RelPath: relPathOr(path, baseDir),
-				Content: []byte(fmt.Sprintf("ERROR: could not read file: %v", err)),
-				Ext:     filepath.Ext(path),
-			})
-			continue
+	return LoadFilesFS(hostFS{}, paths, baseDir)
+}
+
+// hostFS opens names as os.Open does, relative to the working directory.
+// loadFile reads from it by each file's path as given rather than by its
+// path relative to baseDir, which os.DirFS would reject for absolute paths
+// under a relative baseDir and for paths outside baseDir.
+type hostFS struct{}
+
+func (hostFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+// LoadFilesFS is LoadFiles for paths produced by scanning fsys as though it
+// were mounted at baseDir.
+func LoadFilesFS(fsys fs.FS, paths []string, baseDir string) ([]FileInfo, error) {
+	return LoadFilesParallel(fsys, paths, baseDir, 1, false)
+}
+
+// LoadFilesParallel is LoadFilesFS with up to jobs files read at once. The
+// result keeps the order of paths. A jobs value below 1 means one worker per
+// CPU. With meta, each file is also hashed and stat'ed for its SHA256,
+// ModTime and Mode.
+func LoadFilesParallel(fsys fs.FS, paths []string, baseDir string, jobs int, meta bool) ([]FileInfo, error) {
+	if jobs < 1 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	jobs = min(jobs, len(paths))
+
+	infos := make([]FileInfo, len(paths))
+	if jobs <= 1 {
+		for i, path := range paths {
+			infos[i] = loadFile(fsys, path, baseDir, meta)
 		}
+		return infos, nil
+	}
 
-		infos = append(infos, FileInfo{
-			Path:    path,
-			RelPath: relPathOr(path, baseDir),
-			Content: content,
-			Ext:     filepath.Ext(path),
-		})
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				infos[i] = loadFile(fsys, paths[i], baseDir, meta)
+			}
+		}()
 	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
 
 	return infos, nil
 }
 
+// LoadFilesSeq is LoadFilesParallel for streaming: files are read as the
+// sequence is iterated, with at most jobs of them read ahead of the
+// consumer, so memory use does not grow with the number of files.
+func LoadFilesSeq(fsys fs.FS, paths []string, baseDir string, jobs int, meta bool) iter.Seq[FileInfo] {
+	return func(yield func(FileInfo) bool) {
+		if jobs < 1 {
+			jobs = runtime.GOMAXPROCS(0)
+		}
+
+		if jobs <= 1 {
+			for _, path := range paths {
+				if !yield(loadFile(fsys, path, baseDir, meta)) {
+					return
+				}
+			}
+			return
+		}
+
+		results := make([]chan FileInfo, len(paths))
+		for i := range results {
+			results[i] = make(chan FileInfo, 1)
+		}
+
+		slots := make(chan struct{}, jobs)
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			for i, path := range paths {
+				select {
+				case slots <- struct{}{}:
+				case <-done:
+					return
+				}
+				go func() {
+					results[i] <- loadFile(fsys, path, baseDir, meta)
+				}()
+			}
+		}()
+
+		for i := range paths {
+			info := <-results[i]
+			<-slots
+			if !yield(info) {
+				return
+			}
+		}
+	}
+}
+
+// LoadErrors returns the files that could not be read.
+func LoadErrors(files []FileInfo) []FileInfo {
+	var failed []FileInfo
+	for _, f := range files {
+		if f.Err != nil {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
+
+func loadFile(fsys fs.FS, path, baseDir string, meta bool) FileInfo {
+	info := FileInfo{
+		Path:    path,
+		RelPath: relPathOr(path, baseDir),
+		Ext:     filepath.Ext(path),
+	}
+
+	name := filepath.ToSlash(info.RelPath)
+	if _, ok := fsys.(hostFS); ok {
+		name = path
+	}
+
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		info.Err = err
+		info.Content = []byte(fmt.Sprintf("ERROR: could not read file: %v", err))
+		return info
+	}
+	info.Content = content
+	info.Size = int64(len(content))
+	info.Lines = countLines(content)
+	if meta {
+		sum := sha256.Sum256(content)
+		info.SHA256 = hex.EncodeToString(sum[:])
+		if stat, err := fs.Stat(fsys, name); err == nil {
+			info.ModTime = stat.ModTime().UTC()
+			info.Mode = stat.Mode()
+		}
+	}
+	if info.Ext == "" {
+		if l, ok := lang.ForShebang(content); ok {
+			info.Language = l.Name
+		}
+	}
+	return info
+}
+
+func countLines(content []byte) int {
+	if len(content) == 0 {
+		return 0
+	}
+	lines := bytes.Count(content, []byte{'\n'})
+	if content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+func generatedAt(opts Options) time.Time {
+	if opts.GeneratedAt.IsZero() {
+		return time.Now().UTC()
+	}
+	return opts.GeneratedAt
+}
+
 func relPathOr(path, base string) string {
 	if rel, err := filepath.Rel(base, path); err == nil {
 		return rel
@@ -1,8 +1,17 @@
 package processor
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestRegistry(t *testing.T) {
@@ -71,6 +80,291 @@ func TestLoadFiles(t *testing.T) {
 	})
 }
 
+func TestLoadFilesOutsideBaseDir(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	tests := map[string]string{
+		"absolute path under a relative baseDir": ".",
+		"path outside baseDir":                   filepath.Join(dir, "sub"),
+	}
+	for name, baseDir := range tests {
+		t.Run(name, func(t *testing.T) {
+			infos, err := LoadFiles([]string{path}, baseDir)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if infos[0].Err != nil || string(infos[0].Content) != "hello\n" {
+				t.Errorf("expected the file to be read, got %+v", infos[0])
+			}
+		})
+	}
+}
+
+func TestLoadFilesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":     {Data: []byte("package main\n")},
+		"pkg/util.go": {Data: []byte("package pkg\n")},
+	}
+
+	base := filepath.Join("virtual", "root")
+	paths := []string{
+		filepath.Join(base, "main.go"),
+		filepath.Join(base, "pkg", "util.go"),
+		filepath.Join(base, "missing.go"),
+	}
+
+	infos, err := LoadFilesFS(fsys, paths, base)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("expected 3 file infos, got %d", len(infos))
+	}
+
+	if infos[1].RelPath != filepath.Join("pkg", "util.go") || string(infos[1].Content) != "package pkg\n" {
+		t.Errorf("unexpected file info %+v", infos[1])
+	}
+	if !strings.HasPrefix(string(infos[2].Content), "ERROR: could not read file") {
+		t.Errorf("expected error message for missing file, got %q", infos[2].Content)
+	}
+}
+
+func TestLoadFilesShebang(t *testing.T) {
+	fsys := fstest.MapFS{
+		"bin/deploy": {Data: []byte("#!/usr/bin/env python3\nprint('hi')\n")},
+		"bin/notes":  {Data: []byte("plain text\n")},
+		"run.sh":     {Data: []byte("#!/usr/bin/env python3\n")},
+	}
+	paths := []string{
+		filepath.Join("root", "bin", "deploy"),
+		filepath.Join("root", "bin", "notes"),
+		filepath.Join("root", "run.sh"),
+	}
+
+	infos, err := LoadFilesFS(fsys, paths, "root")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for i, want := range []string{"python", "", ""} {
+		if infos[i].Language != want {
+			t.Errorf("%s: expected language %q, got %q", infos[i].RelPath, want, infos[i].Language)
+		}
+	}
+	if lang := fileLanguage(infos[0]); lang != "python" {
+		t.Errorf("expected python fence, got %q", lang)
+	}
+	if lang := fileLanguage(infos[2]); lang != "bash" {
+		t.Errorf("expected the extension to decide the fence for run.sh, got %q", lang)
+	}
+}
+
+func TestLoadFilesMeta(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("hello\nworld"), Mode: 0640, ModTime: modTime},
+	}
+
+	paths := []string{filepath.Join("root", "a.txt"), filepath.Join("root", "missing.txt")}
+	infos, err := LoadFilesParallel(fsys, paths, "root", 1, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got := infos[0]
+	if got.Size != 11 || got.Lines != 2 {
+		t.Errorf("expected 11 bytes and 2 lines, got %d and %d", got.Size, got.Lines)
+	}
+	if !got.ModTime.Equal(modTime) || got.ModTime.Location() != time.UTC {
+		t.Errorf("expected mod time %v in UTC, got %v", modTime, got.ModTime)
+	}
+	if got.Mode != 0640 {
+		t.Errorf("expected mode 0640, got %v", got.Mode)
+	}
+	if want := "26c60a61d01db5836ca70fefd44a6a016620413c8ef5f259a6c5612d4f79d3b8"; got.SHA256 != want {
+		t.Errorf("expected sha256 %s, got %s", want, got.SHA256)
+	}
+
+	if infos[1].Err == nil || infos[1].SHA256 != "" {
+		t.Errorf("expected no metadata for an unreadable file, got %+v", infos[1])
+	}
+
+	infos, err = LoadFilesParallel(fsys, paths, "root", 1, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := infos[0]; got.Size != 11 || got.SHA256 != "" || !got.ModTime.IsZero() || got.Mode != 0 {
+		t.Errorf("expected only size and lines without meta, got %+v", got)
+	}
+}
+
+func TestLoadFilesParallel(t *testing.T) {
+	fsys := fstest.MapFS{}
+	var paths []string
+	for i := range 50 {
+		name := fmt.Sprintf("pkg%d/file%02d.go", i%4, i)
+		fsys[name] = &fstest.MapFile{Data: []byte(name)}
+		paths = append(paths, filepath.Join("root", filepath.FromSlash(name)))
+	}
+	paths = append(paths, filepath.Join("root", "missing.go"))
+
+	for _, jobs := range []int{0, 1, 8} {
+		t.Run(fmt.Sprintf("jobs=%d", jobs), func(t *testing.T) {
+			infos, err := LoadFilesParallel(fsys, paths, "root", jobs, false)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(infos) != len(paths) {
+				t.Fatalf("expected %d file infos, got %d", len(paths), len(infos))
+			}
+
+			for i, info := range infos[:len(infos)-1] {
+				if info.Path != paths[i] {
+					t.Fatalf("expected %s at position %d, got %s", paths[i], i, info.Path)
+				}
+				if string(info.Content) != filepath.ToSlash(info.RelPath) || info.Err != nil {
+					t.Errorf("unexpected file info %+v", info)
+				}
+			}
+
+			failed := LoadErrors(infos)
+			if len(failed) != 1 || failed[0].RelPath != "missing.go" {
+				t.Fatalf("expected missing.go to fail, got %+v", failed)
+			}
+			if !errors.Is(failed[0].Err, fs.ErrNotExist) {
+				t.Errorf("expected ErrNotExist, got %v", failed[0].Err)
+			}
+		})
+	}
+}
+
+func TestLoadFilesSeq(t *testing.T) {
+	fsys := fstest.MapFS{}
+	var paths []string
+	for i := range 20 {
+		name := fmt.Sprintf("file%02d.go", i)
+		fsys[name] = &fstest.MapFile{Data: []byte(name)}
+		paths = append(paths, filepath.Join("root", name))
+	}
+
+	for _, jobs := range []int{1, 4} {
+		t.Run(fmt.Sprintf("jobs=%d", jobs), func(t *testing.T) {
+			i := 0
+			for info := range LoadFilesSeq(fsys, paths, "root", jobs, false) {
+				if info.Path != paths[i] || string(info.Content) != info.RelPath {
+					t.Fatalf("unexpected file info at position %d: %+v", i, info)
+				}
+				i++
+			}
+			if i != len(paths) {
+				t.Fatalf("expected %d files, got %d", len(paths), i)
+			}
+
+			i = 0
+			for range LoadFilesSeq(fsys, paths, "root", jobs, false) {
+				if i++; i == 3 {
+					break
+				}
+			}
+			if i != 3 {
+				t.Errorf("expected iteration to stop after 3 files, got %d", i)
+			}
+		})
+	}
+}
+
+func TestProcessTo(t *testing.T) {
+	files := []FileInfo{
+		{Path: "/p/main.go", RelPath: "main.go", Content: []byte("package main\n"), Ext: ".go"},
+		{Path: "/p/a/b.txt", RelPath: "a/b.txt", Content: []byte("<b> & ]]>"), Ext: ".txt"},
+	}
+	opts := Options{
+		HeadingLevel: 2,
+		GeneratedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Deleted:      []string{"gone.go"},
+	}
+
+	for _, p := range []Processor{NewMarkdownProcessor(), NewXMLProcessor(), NewJSONProcessor()} {
+		for _, input := range [][]FileInfo{files, nil} {
+			t.Run(fmt.Sprintf("%s/%d", p.Name(), len(input)), func(t *testing.T) {
+				want, err := p.Process(input, opts)
+				if err != nil {
+					t.Fatalf("Process: %v", err)
+				}
+
+				var got bytes.Buffer
+				if err := ProcessTo(p, &got, slices.Values(input), opts); err != nil {
+					t.Fatalf("ProcessTo: %v", err)
+				}
+				if got.String() != string(want) {
+					t.Errorf("streamed output differs from Process:\n%s\nwant:\n%s", got.String(), want)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkLoadFiles(b *testing.B) {
+	dir := b.TempDir()
+	content := bytes.Repeat([]byte("package main // padding\n"), 512)
+
+	var paths []string
+	for i := range 256 {
+		path := filepath.Join(dir, fmt.Sprintf("file%03d.go", i))
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			b.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	b.Run("sequential", func(b *testing.B) {
+		for range b.N {
+			if _, err := LoadFiles(paths, dir); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parallel-8", func(b *testing.B) {
+		fsys := os.DirFS(dir)
+		for range b.N {
+			if _, err := LoadFilesParallel(fsys, paths, dir, 8, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestDeletedFiles(t *testing.T) {
+	files := []FileInfo{{RelPath: "main.go", Content: []byte("package main\n"), Ext: ".go"}}
+	opts := Options{HeadingLevel: 2, Deleted: []string{filepath.Join("old", "gone.go")}}
+
+	tests := []struct {
+		proc     Processor
+		expected string
+	}{
+		{NewMarkdownProcessor(), "## Deleted files\n\n- `old/gone.go`\n"},
+		{NewJSONProcessor(), "\"deleted\": [\n    \"old/gone.go\"\n  ]"},
+		{NewXMLProcessor(), "<deleted_files>\n<file>old/gone.go</file>\n</deleted_files>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.proc.Name(), func(t *testing.T) {
+			result, err := tt.proc.Process(files, opts)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !strings.Contains(string(result), tt.expected) {
+				t.Errorf("expected %q in output:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestRelPathOr(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	OversizeSkip     = "skip"
+	OversizeTruncate = "truncate"
+)
+
+var OversizeModes = []string{OversizeSkip, OversizeTruncate}
+
+const (
+	LimitFileSize  = "file size"
+	LimitTotalSize = "total size"
+)
+
+// SizeLimits caps the size of each loaded file and of all of them
+// together. A zero limit means no limit.
+type SizeLimits struct {
+	MaxFileSize  int64
+	MaxTotalSize int64
+	Mode         string
+}
+
+// SizeBudget applies SizeLimits to files in the order they are loaded.
+type SizeBudget struct {
+	limits SizeLimits
+	used   int64
+}
+
+func NewSizeBudget(limits SizeLimits) *SizeBudget {
+	return &SizeBudget{limits: limits}
+}
+
+// Apply checks file against the limits and returns the limit it exceeded,
+// LimitFileSize or LimitTotalSize, or "" if it fits. In truncate mode a file
+// over a limit is cut to fit and ends with a "[truncated N bytes]" marker;
+// otherwise, or once the total is used up, keep is false and the file should
+// be left out. Files that could not be read are passed through untouched.
+func (b *SizeBudget) Apply(file FileInfo) (out FileInfo, exceeded string, keep bool) {
+	if file.Err != nil {
+		return file, "", true
+	}
+
+	size := int64(len(file.Content))
+	if b.limits.MaxFileSize > 0 && size > b.limits.MaxFileSize {
+		size, exceeded = b.limits.MaxFileSize, LimitFileSize
+	}
+	if b.limits.MaxTotalSize > 0 && b.used+size > b.limits.MaxTotalSize {
+		size, exceeded = b.limits.MaxTotalSize-b.used, LimitTotalSize
+	}
+
+	if exceeded == "" {
+		b.used += size
+		return file, "", true
+	}
+	if b.limits.Mode != OversizeTruncate || size <= 0 {
+		return file, exceeded, false
+	}
+
+	file.Content = truncateBytes(file.Content, int(size))
+	b.used += size
+	return file, exceeded, true
+}
+
+// truncateBytes cuts content to at most n bytes, backing off to a rune
+// boundary, and appends a marker saying how much was removed.
+func truncateBytes(content []byte, n int) []byte {
+	for n > 0 && n < len(content) && !utf8.RuneStart(content[n]) {
+		n--
+	}
+
+	out := make([]byte, 0, n+32)
+	out = append(out, content[:n]...)
+	if n > 0 && content[n-1] != '\n' {
+		out = append(out, '\n')
+	}
+	return fmt.Appendf(out, "[truncated %d bytes]\n", len(content)-n)
+}
@@ -0,0 +1,105 @@
+package processor
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestSizeBudget(t *testing.T) {
+	file := func(name string, size int) FileInfo {
+		return FileInfo{RelPath: name, Content: []byte(strings.Repeat("x", size))}
+	}
+
+	type result struct {
+		content  string
+		exceeded string
+		keep     bool
+	}
+
+	tests := []struct {
+		name   string
+		limits SizeLimits
+		files  []FileInfo
+		want   []result
+	}{
+		{
+			name:   "no limits",
+			limits: SizeLimits{Mode: OversizeSkip},
+			files:  []FileInfo{file("a", 10)},
+			want:   []result{{strings.Repeat("x", 10), "", true}},
+		},
+		{
+			name:   "skip over file size",
+			limits: SizeLimits{MaxFileSize: 5, Mode: OversizeSkip},
+			files:  []FileInfo{file("a", 5), file("b", 6)},
+			want: []result{
+				{"xxxxx", "", true},
+				{"xxxxxx", LimitFileSize, false},
+			},
+		},
+		{
+			name:   "truncate over file size",
+			limits: SizeLimits{MaxFileSize: 4, Mode: OversizeTruncate},
+			files:  []FileInfo{file("a", 10)},
+			want:   []result{{"xxxx\n[truncated 6 bytes]\n", LimitFileSize, true}},
+		},
+		{
+			name:   "skip over total size keeps later files that fit",
+			limits: SizeLimits{MaxTotalSize: 10, Mode: OversizeSkip},
+			files:  []FileInfo{file("a", 6), file("b", 6), file("c", 4)},
+			want: []result{
+				{"xxxxxx", "", true},
+				{"xxxxxx", LimitTotalSize, false},
+				{"xxxx", "", true},
+			},
+		},
+		{
+			name:   "truncate over total size then skip",
+			limits: SizeLimits{MaxTotalSize: 10, Mode: OversizeTruncate},
+			files:  []FileInfo{file("a", 6), file("b", 6), file("c", 1)},
+			want: []result{
+				{"xxxxxx", "", true},
+				{"xxxx\n[truncated 2 bytes]\n", LimitTotalSize, true},
+				{"x", LimitTotalSize, false},
+			},
+		},
+		{
+			name:   "unreadable files pass through",
+			limits: SizeLimits{MaxFileSize: 1, Mode: OversizeSkip},
+			files:  []FileInfo{{RelPath: "a", Content: []byte("ERROR: could not read file"), Err: fs.ErrPermission}},
+			want:   []result{{"ERROR: could not read file", "", true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := NewSizeBudget(tt.limits)
+			for i, f := range tt.files {
+				out, exceeded, keep := budget.Apply(f)
+				got := result{string(out.Content), exceeded, keep}
+				if got != tt.want[i] {
+					t.Errorf("file %s: expected %+v, got %+v", f.RelPath, tt.want[i], got)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncateBytes(t *testing.T) {
+	tests := []struct {
+		content string
+		n       int
+		want    string
+	}{
+		{"line one\nline two\n", 9, "line one\n[truncated 9 bytes]\n"},
+		{"héllo", 2, "h\n[truncated 5 bytes]\n"},
+		{"abc", 0, "[truncated 3 bytes]\n"},
+	}
+
+	for _, tt := range tests {
+		if got := string(truncateBytes([]byte(tt.content), tt.n)); got != tt.want {
+			t.Errorf("truncateBytes(%q, %d) = %q, want %q", tt.content, tt.n, got, tt.want)
+		}
+	}
+}
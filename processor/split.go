@@ -0,0 +1,267 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"amalgo/tokenizer"
+)
+
+type PartInfo struct {
+	Index int
+	Total int
+	Files []PartFile
+}
+
+// PartFile names a file in a part. StartLine and EndLine are set when the
+// part only holds a range of the file's lines.
+type PartFile struct {
+	RelPath   string
+	StartLine int
+	EndLine   int
+}
+
+type SplitLimits struct {
+	MaxBytes  int
+	MaxTokens int
+}
+
+type Part struct {
+	Index   int
+	Files   []FileInfo
+	Content []byte
+}
+
+type splitter struct {
+	proc   Processor
+	opts   Options
+	limits SplitLimits
+	enc    tokenizer.Encoder
+	// placeholder stands in for part numbers while parts are measured,
+	// with as many digits as the largest one.
+	placeholder int
+}
+
+// Split renders files with p into parts that each stay within limits.
+// Files are never broken across parts unless a single file exceeds the
+// limit on its own, in which case it is cut at line boundaries.
+func Split(p Processor, files []FileInfo, opts Options, limits SplitLimits, enc tokenizer.Encoder) ([]Part, error) {
+	if limits.MaxBytes <= 0 && limits.MaxTokens <= 0 {
+		return nil, errors.New("split requires a byte or token limit")
+	}
+	if limits.MaxTokens > 0 && enc == nil {
+		return nil, errors.New("split by tokens requires a tokenizer")
+	}
+
+	// Part numbers are not known until packing is done, so parts are
+	// measured with a placeholder count. Should there turn out to be more
+	// parts than it allows for, packing is redone with a wider one.
+	s := splitter{proc: p, opts: opts, limits: limits, enc: enc, placeholder: 999}
+	var groups [][]chunk
+	for {
+		var chunks []chunk
+		for _, file := range files {
+			fileChunks, err := s.chunkFile(file)
+			if err != nil {
+				return nil, err
+			}
+			chunks = append(chunks, fileChunks...)
+		}
+
+		var err error
+		groups, err = s.pack(chunks)
+		if err != nil {
+			return nil, err
+		}
+		if len(groups) <= s.placeholder {
+			break
+		}
+		s.placeholder = s.placeholder*10 + 9
+	}
+
+	parts := make([]Part, len(groups))
+	for i, group := range groups {
+		content, err := s.render(group, i+1, len(groups))
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = Part{Index: i + 1, Content: content}
+		for _, c := range group {
+			parts[i].Files = append(parts[i].Files, c.file)
+		}
+	}
+
+	return parts, nil
+}
+
+type chunk struct {
+	file  FileInfo
+	label PartFile
+	cost  int
+}
+
+func (s splitter) measure(content []byte) int {
+	if s.limits.MaxTokens > 0 {
+		return s.enc.Count(content)
+	}
+	return len(content)
+}
+
+func (s splitter) limit() int {
+	if s.limits.MaxTokens > 0 {
+		return s.limits.MaxTokens
+	}
+	return s.limits.MaxBytes
+}
+
+func (s splitter) render(group []chunk, index, total int) ([]byte, error) {
+	opts := s.opts
+	opts.Part = &PartInfo{Index: index, Total: total}
+	if index != total {
+		opts.Deleted = nil
+	}
+
+	files := make([]FileInfo, len(group))
+	for i, c := range group {
+		files[i] = c.file
+		opts.Part.Files = append(opts.Part.Files, c.label)
+	}
+
+	return s.proc.Process(files, opts)
+}
+
+// chunkFile measures a file's standalone cost and, if it cannot fit in a
+// part by itself, cuts it into line ranges that can.
+func (s splitter) chunkFile(file FileInfo) ([]chunk, error) {
+	whole := chunk{file: file, label: PartFile{RelPath: file.RelPath}}
+
+	out, err := s.render([]chunk{whole}, s.placeholder, s.placeholder)
+	if err != nil {
+		return nil, err
+	}
+	whole.cost = s.measure(out)
+	if whole.cost <= s.limit() {
+		return []chunk{whole}, nil
+	}
+
+	lines := splitLines(file.Content)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s: the file's heading alone exceeds the split limit", filepath.ToSlash(file.RelPath))
+	}
+	var chunks []chunk
+	for start := 0; start < len(lines); {
+		lo, hi := start+1, len(lines)
+		for lo < hi {
+			mid := (lo + hi + 1) / 2
+			c := lineChunk(file, lines, start, mid)
+			out, err := s.render([]chunk{c}, s.placeholder, s.placeholder)
+			if err != nil {
+				return nil, err
+			}
+			if s.measure(out) <= s.limit() {
+				lo = mid
+			} else {
+				hi = mid - 1
+			}
+		}
+
+		c := lineChunk(file, lines, start, lo)
+		out, err := s.render([]chunk{c}, s.placeholder, s.placeholder)
+		if err != nil {
+			return nil, err
+		}
+		c.cost = s.measure(out)
+		if c.cost > s.limit() {
+			return nil, fmt.Errorf("%s: line %d alone exceeds the split limit", filepath.ToSlash(file.RelPath), start+1)
+		}
+
+		chunks = append(chunks, c)
+		start = lo
+	}
+
+	return chunks, nil
+}
+
+func lineChunk(file FileInfo, lines [][]byte, start, end int) chunk {
+	var content []byte
+	for _, line := range lines[start:end] {
+		content = append(content, line...)
+	}
+	file.Content = content
+
+	return chunk{
+		file:  file,
+		label: PartFile{RelPath: file.RelPath, StartLine: start + 1, EndLine: end},
+	}
+}
+
+func splitLines(content []byte) [][]byte {
+	var lines [][]byte
+	for start := 0; start < len(content); {
+		end := start
+		for end < len(content) && content[end] != '\n' {
+			end++
+		}
+		if end < len(content) {
+			end++
+		}
+		lines = append(lines, content[start:end])
+		start = end
+	}
+	return lines
+}
+
+// pack groups chunks greedily using their standalone cost, then verifies
+// each group by rendering it and moves trailing chunks into a new group
+// while a rendered part is still over the limit.
+func (s splitter) pack(chunks []chunk) ([][]chunk, error) {
+	empty, err := s.render(nil, s.placeholder, s.placeholder)
+	if err != nil {
+		return nil, err
+	}
+	base := s.measure(empty)
+
+	var groups [][]chunk
+	var current []chunk
+	used := base
+	for _, c := range chunks {
+		cost := c.cost - base
+		if len(current) > 0 && used+cost > s.limit() {
+			groups = append(groups, current)
+			current, used = nil, base
+		}
+		current = append(current, c)
+		used += cost
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+
+	for i := 0; i < len(groups); i++ {
+		for {
+			out, err := s.render(groups[i], s.placeholder, s.placeholder)
+			if err != nil {
+				return nil, err
+			}
+			if s.measure(out) <= s.limit() || len(groups[i]) == 1 {
+				break
+			}
+
+			last := groups[i][len(groups[i])-1]
+			groups[i] = groups[i][:len(groups[i])-1]
+			if i+1 == len(groups) {
+				groups = append(groups, nil)
+			}
+			groups[i+1] = append([]chunk{last}, groups[i+1]...)
+		}
+	}
+
+	return groups, nil
+}
+
+// PartPath inserts ".partN" before the extension of path.
+func PartPath(path string, index int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.part%d%s", path[:len(path)-len(ext)], index, ext)
+}
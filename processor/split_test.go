@@ -0,0 +1,188 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"amalgo/tokenizer"
+)
+
+func TestSplit(t *testing.T) {
+	proc := NewMarkdownProcessor()
+	files := []FileInfo{
+		{RelPath: "a.go", Content: bytes.Repeat([]byte("// a\n"), 40), Ext: ".go"},
+		{RelPath: "b.go", Content: bytes.Repeat([]byte("// b\n"), 40), Ext: ".go"},
+		{RelPath: "c.go", Content: bytes.Repeat([]byte("// c\n"), 40), Ext: ".go"},
+	}
+
+	t.Run("requires a limit", func(t *testing.T) {
+		if _, err := Split(proc, files, Options{}, SplitLimits{}, nil); err == nil {
+			t.Error("expected error without limits")
+		}
+	})
+
+	t.Run("everything fits in one part", func(t *testing.T) {
+		parts, err := Split(proc, files, Options{HeadingLevel: 1}, SplitLimits{MaxBytes: 1 << 20}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(parts) != 1 || len(parts[0].Files) != 3 {
+			t.Fatalf("expected a single part with 3 files, got %d parts", len(parts))
+		}
+		if !strings.Contains(string(parts[0].Content), "# Part 1 of 1") {
+			t.Error("expected part header")
+		}
+	})
+
+	t.Run("files are not broken across parts", func(t *testing.T) {
+		limit := 450
+		parts, err := Split(proc, files, Options{HeadingLevel: 1}, SplitLimits{MaxBytes: limit}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(parts) < 2 {
+			t.Fatalf("expected multiple parts, got %d", len(parts))
+		}
+
+		seen := 0
+		for i, part := range parts {
+			if len(part.Content) > limit {
+				t.Errorf("part %d has %d bytes, limit is %d", i+1, len(part.Content), limit)
+			}
+			if part.Index != i+1 {
+				t.Errorf("expected index %d, got %d", i+1, part.Index)
+			}
+
+			output := string(part.Content)
+			header := "# Part " + string(rune('0'+part.Index)) + " of " + string(rune('0'+len(parts)))
+			if !strings.HasPrefix(output, header) {
+				t.Errorf("part %d: expected header %q, got %q", i+1, header, output[:min(len(output), 40)])
+			}
+			for _, f := range part.Files {
+				if !bytes.Equal(f.Content, files[seen].Content) {
+					t.Errorf("file %s was modified", f.RelPath)
+				}
+				if !strings.Contains(output, "- `"+f.RelPath+"`\n") {
+					t.Errorf("part %d header does not list %s", i+1, f.RelPath)
+				}
+				seen++
+			}
+		}
+		if seen != len(files) {
+			t.Errorf("expected %d files across parts, got %d", len(files), seen)
+		}
+	})
+
+	t.Run("oversized file is cut at line boundaries", func(t *testing.T) {
+		big := []FileInfo{
+			{RelPath: "big.go", Content: bytes.Repeat([]byte("// line\n"), 100), Ext: ".go"},
+		}
+		limit := 300
+
+		parts, err := Split(proc, big, Options{HeadingLevel: 1}, SplitLimits{MaxBytes: limit}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(parts) < 3 {
+			t.Fatalf("expected the file to be split into several parts, got %d", len(parts))
+		}
+
+		var rebuilt []byte
+		for i, part := range parts {
+			if len(part.Content) > limit {
+				t.Errorf("part %d has %d bytes, limit is %d", i+1, len(part.Content), limit)
+			}
+			if !strings.Contains(string(part.Content), "- `big.go` (lines ") {
+				t.Errorf("part %d header should list the line range", i+1)
+			}
+			rebuilt = append(rebuilt, part.Files[0].Content...)
+		}
+		if !bytes.Equal(rebuilt, big[0].Content) {
+			t.Error("chunks do not reassemble the original file")
+		}
+	})
+
+	t.Run("empty file whose heading is over the limit", func(t *testing.T) {
+		empty := []FileInfo{{RelPath: strings.Repeat("long/", 40) + "empty.go", Ext: ".go"}}
+
+		if _, err := Split(proc, empty, Options{HeadingLevel: 1}, SplitLimits{MaxBytes: 100}, nil); err == nil {
+			t.Error("expected error instead of dropping the file")
+		}
+	})
+
+	t.Run("more than 999 parts", func(t *testing.T) {
+		var many []FileInfo
+		for i := range 1200 {
+			many = append(many, FileInfo{RelPath: fmt.Sprintf("f%05d.go", i), Content: []byte("package f\n"), Ext: ".go"})
+		}
+		// A lone file fits under a three-digit part number, but its own
+		// header is two bytes longer in parts 1000 to 1200 of 1200.
+		one, err := proc.Process(many[:1], Options{HeadingLevel: 1, Part: &PartInfo{Index: 999, Total: 999, Files: []PartFile{{RelPath: many[0].RelPath}}}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := Split(proc, many, Options{HeadingLevel: 1}, SplitLimits{MaxBytes: len(one) + 1}, nil); err == nil {
+			t.Error("expected error for parts that only fit a three-digit header")
+		}
+
+		limit := len(one) + 2
+		parts, err := Split(proc, many, Options{HeadingLevel: 1}, SplitLimits{MaxBytes: limit}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(parts) != len(many) {
+			t.Fatalf("expected %d parts, got %d", len(many), len(parts))
+		}
+		for i, part := range parts {
+			if len(part.Content) > limit {
+				t.Errorf("part %d has %d bytes, limit is %d", i+1, len(part.Content), limit)
+			}
+		}
+	})
+
+	t.Run("split by tokens", func(t *testing.T) {
+		enc := tokenizer.NewHeuristic()
+		limit := 120
+
+		parts, err := Split(proc, files, Options{HeadingLevel: 1}, SplitLimits{MaxTokens: limit}, enc)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i, part := range parts {
+			if n := enc.Count(part.Content); n > limit {
+				t.Errorf("part %d has %d tokens, limit is %d", i+1, n, limit)
+			}
+		}
+	})
+
+	t.Run("json part metadata", func(t *testing.T) {
+		parts, err := Split(NewJSONProcessor(), files, Options{}, SplitLimits{MaxBytes: 1 << 20}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(string(parts[0].Content), `"total": 1`) {
+			t.Error("expected part metadata in JSON output")
+		}
+	})
+}
+
+func TestPartPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		index    int
+		expected string
+	}{
+		{"concat.md", 1, "concat.part1.md"},
+		{"out/bundle.json", 12, "out/bundle.part12.json"},
+		{"noext", 2, "noext.part2"},
+	}
+
+	for _, tt := range tests {
+		if got := PartPath(tt.path, tt.index); got != tt.expected {
+			t.Errorf("PartPath(%q, %d): expected %q, got %q", tt.path, tt.index, tt.expected, got)
+		}
+	}
+}
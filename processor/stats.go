@@ -0,0 +1,149 @@
+package processor
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"amalgo/lang"
+	"amalgo/tokenizer"
+)
+
+// Names used in a StatsReport for files that fall in no group.
+const (
+	NoExtension = "(none)"
+	NoLanguage  = "(unknown)"
+	RootDir     = "."
+)
+
+// StatsRow totals the files in one group of a StatsReport.
+type StatsRow struct {
+	Name   string `json:"name"`
+	Files  int    `json:"files"`
+	Bytes  int64  `json:"bytes"`
+	Lines  int    `json:"lines"`
+	Tokens int    `json:"tokens"`
+}
+
+type FileStats struct {
+	Path   string `json:"path"`
+	Bytes  int64  `json:"bytes"`
+	Lines  int    `json:"lines"`
+	Tokens int    `json:"tokens"`
+}
+
+type StatsReport struct {
+	Encoder     string      `json:"tokenizer"`
+	Total       StatsRow    `json:"total"`
+	Extensions  []StatsRow  `json:"extensions"`
+	Directories []StatsRow  `json:"directories"`
+	Languages   []StatsRow  `json:"languages"`
+	Largest     []FileStats `json:"largest"`
+}
+
+// Stats accumulates counts for the files added to it, so that they can be
+// gathered as files stream past rather than from a collected slice.
+type Stats struct {
+	enc   tokenizer.Encoder
+	total StatsRow
+	exts  map[string]*StatsRow
+	dirs  map[string]*StatsRow
+	langs map[string]*StatsRow
+	files []FileStats
+}
+
+func NewStats(enc tokenizer.Encoder) *Stats {
+	return &Stats{
+		enc:   enc,
+		total: StatsRow{Name: "total"},
+		exts:  make(map[string]*StatsRow),
+		dirs:  make(map[string]*StatsRow),
+		langs: make(map[string]*StatsRow),
+	}
+}
+
+// Add counts file as it will be written, after any truncation or binary
+// placeholder has replaced its content.
+func (s *Stats) Add(file FileInfo) {
+	fs := FileStats{
+		Path:   filepath.ToSlash(file.RelPath),
+		Bytes:  int64(len(file.Content)),
+		Lines:  countLines(file.Content),
+		Tokens: s.enc.Count(file.Content),
+	}
+	s.files = append(s.files, fs)
+
+	ext := strings.ToLower(file.Ext)
+	if ext == "" {
+		ext = NoExtension
+	}
+	dir, _, found := strings.Cut(fs.Path, "/")
+	if !found {
+		dir = RootDir
+	}
+
+	for _, row := range []*StatsRow{&s.total, group(s.exts, ext), group(s.dirs, dir), group(s.langs, statsLanguage(file))} {
+		row.Files++
+		row.Bytes += fs.Bytes
+		row.Lines += fs.Lines
+		row.Tokens += fs.Tokens
+	}
+}
+
+// Report returns the totals with each group ordered by descending size,
+// and up to largest of the biggest files. A non-positive largest lists
+// every file.
+func (s *Stats) Report(largest int) StatsReport {
+	files := make([]FileStats, len(s.files))
+	copy(files, s.files)
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Bytes > files[j].Bytes
+	})
+	if largest > 0 && largest < len(files) {
+		files = files[:largest]
+	}
+
+	return StatsReport{
+		Encoder:     s.enc.Name(),
+		Total:       s.total,
+		Extensions:  sortedRows(s.exts),
+		Directories: sortedRows(s.dirs),
+		Languages:   sortedRows(s.langs),
+		Largest:     files,
+	}
+}
+
+func group(groups map[string]*StatsRow, name string) *StatsRow {
+	row, ok := groups[name]
+	if !ok {
+		row = &StatsRow{Name: name}
+		groups[name] = row
+	}
+	return row
+}
+
+func sortedRows(groups map[string]*StatsRow) []StatsRow {
+	rows := make([]StatsRow, 0, len(groups))
+	for _, row := range groups {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Bytes != rows[j].Bytes {
+			return rows[i].Bytes > rows[j].Bytes
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows
+}
+
+// statsLanguage names file's language in the lang catalogue, as detected
+// from its "#!" line or judged by its name.
+func statsLanguage(file FileInfo) string {
+	if l, ok := lang.Lookup(file.Language); ok {
+		return l.Name
+	}
+	if l, ok := lang.ForFilename(filepath.Base(file.RelPath)); ok {
+		return l.Name
+	}
+	return NoLanguage
+}
@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"amalgo/tokenizer"
+)
+
+func TestStats(t *testing.T) {
+	stats := NewStats(tokenizer.NewHeuristic())
+	for _, f := range []FileInfo{
+		{RelPath: "main.go", Ext: ".go", Content: []byte("package main\n")},
+		{RelPath: "cmd/root.go", Ext: ".go", Content: []byte("package cmd\n\nfunc run() {}\n")},
+		{RelPath: "cmd/README.MD", Ext: ".MD", Content: []byte("# cmd\n")},
+		{RelPath: "bin/deploy", Content: []byte("#!/bin/sh\n"), Language: "shell"},
+		{RelPath: "LICENSE", Content: []byte("MIT\n")},
+	} {
+		stats.Add(f)
+	}
+
+	report := stats.Report(2)
+
+	if want := (StatsRow{Name: "total", Files: 5, Bytes: 60, Lines: 7, Tokens: 17}); report.Total != want {
+		t.Errorf("expected total %+v, got %+v", want, report.Total)
+	}
+
+	wantExts := []StatsRow{
+		{Name: ".go", Files: 2, Bytes: 40, Lines: 4, Tokens: 11},
+		{Name: NoExtension, Files: 2, Bytes: 14, Lines: 2, Tokens: 4},
+		{Name: ".md", Files: 1, Bytes: 6, Lines: 1, Tokens: 2},
+	}
+	if !reflect.DeepEqual(report.Extensions, wantExts) {
+		t.Errorf("expected extensions %+v, got %+v", wantExts, report.Extensions)
+	}
+
+	var dirs, langs []string
+	for _, row := range report.Directories {
+		dirs = append(dirs, row.Name)
+	}
+	for _, row := range report.Languages {
+		langs = append(langs, row.Name)
+	}
+	if want := []string{"cmd", RootDir, "bin"}; !reflect.DeepEqual(dirs, want) {
+		t.Errorf("expected directories %v, got %v", want, dirs)
+	}
+	if want := []string{"go", "shell", "markdown", NoLanguage}; !reflect.DeepEqual(langs, want) {
+		t.Errorf("expected languages %v, got %v", want, langs)
+	}
+
+	wantLargest := []FileStats{
+		{Path: "cmd/root.go", Bytes: 27, Lines: 3, Tokens: 7},
+		{Path: "main.go", Bytes: 13, Lines: 1, Tokens: 4},
+	}
+	if !reflect.DeepEqual(report.Largest, wantLargest) {
+		t.Errorf("expected largest %+v, got %+v", wantLargest, report.Largest)
+	}
+	if report.Encoder != "heuristic" {
+		t.Errorf("expected heuristic tokenizer, got %q", report.Encoder)
+	}
+}
@@ -0,0 +1,51 @@
+package processor
+
+import (
+	"sort"
+
+	"amalgo/tokenizer"
+)
+
+type FileTokens struct {
+	RelPath string
+	Tokens  int
+}
+
+type TokenReport struct {
+	Encoder string
+	Total   int
+	Files   []FileTokens
+}
+
+func CountTokens(files []FileInfo, enc tokenizer.Encoder) TokenReport {
+	report := TokenReport{
+		Encoder: enc.Name(),
+		Files:   make([]FileTokens, 0, len(files)),
+	}
+
+	for _, file := range files {
+		n := enc.Count(file.Content)
+		report.Total += n
+		report.Files = append(report.Files, FileTokens{
+			RelPath: file.RelPath,
+			Tokens:  n,
+		})
+	}
+
+	return report
+}
+
+// Largest returns up to n files ordered by descending token count. A
+// non-positive n returns every file.
+func (r TokenReport) Largest(n int) []FileTokens {
+	sorted := make([]FileTokens, len(r.Files))
+	copy(sorted, r.Files)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Tokens > sorted[j].Tokens
+	})
+
+	if n > 0 && n < len(sorted) {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
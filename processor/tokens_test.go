@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"testing"
+
+	"amalgo/tokenizer"
+)
+
+func TestCountTokens(t *testing.T) {
+	files := []FileInfo{
+		{RelPath: "small.go", Content: []byte("abcd")},
+		{RelPath: "large.go", Content: []byte("abcdefghijklmnop")},
+		{RelPath: "empty.go", Content: []byte{}},
+		{RelPath: "medium.go", Content: []byte("abcdefgh")},
+	}
+
+	report := CountTokens(files, tokenizer.NewHeuristic())
+
+	if report.Encoder != "heuristic" {
+		t.Errorf("expected encoder 'heuristic', got '%s'", report.Encoder)
+	}
+	if report.Total != 7 {
+		t.Errorf("expected 7 tokens in total, got %d", report.Total)
+	}
+	if len(report.Files) != 4 || report.Files[0].RelPath != "small.go" {
+		t.Fatalf("expected files in input order, got %+v", report.Files)
+	}
+
+	t.Run("Largest", func(t *testing.T) {
+		top := report.Largest(2)
+		if len(top) != 2 {
+			t.Fatalf("expected 2 files, got %d", len(top))
+		}
+		if top[0].RelPath != "large.go" || top[1].RelPath != "medium.go" {
+			t.Errorf("unexpected order: %+v", top)
+		}
+
+		if all := report.Largest(0); len(all) != 4 {
+			t.Errorf("expected all 4 files, got %d", len(all))
+		}
+		if report.Files[0].RelPath != "small.go" {
+			t.Error("Largest should not reorder the report")
+		}
+	})
+}
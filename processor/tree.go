@@ -0,0 +1,137 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	TreePlain = "none"
+	TreeSize  = "size"
+	TreeLines = "lines"
+)
+
+var TreeAnnotations = []string{TreePlain, TreeSize, TreeLines}
+
+// Tree is the directory tree of the files in a bundle, shown before their
+// contents.
+type Tree struct {
+	Root     *TreeNode
+	Annotate string
+}
+
+// TreeNode is a file or directory in a Tree. Children are kept in the order
+// the files were given; files have none. Size and Lines are only set on
+// files, and only when the tree is annotated with them.
+type TreeNode struct {
+	Name     string
+	Dir      bool
+	Size     int64
+	Lines    int
+	Children []*TreeNode
+}
+
+// NewTree builds the tree of files, annotating each with the size or line
+// count it was read with when annotate asks for them. Files that could not
+// be read are left unannotated.
+func NewTree(files []FileInfo, annotate string) (*Tree, error) {
+	switch annotate {
+	case TreePlain, TreeSize, TreeLines:
+	default:
+		return nil, fmt.Errorf("unknown tree annotation: %s (expected one of %s)", annotate, strings.Join(TreeAnnotations, ", "))
+	}
+
+	root := &TreeNode{Name: ".", Dir: true}
+	for _, f := range files {
+		node := root
+		parts := strings.Split(filepath.ToSlash(f.RelPath), "/")
+		for _, name := range parts[:len(parts)-1] {
+			node = node.child(name, true)
+		}
+		file := node.child(parts[len(parts)-1], false)
+
+		switch annotate {
+		case TreeSize:
+			file.Size = f.Size
+		case TreeLines:
+			file.Lines = f.Lines
+		}
+	}
+
+	return &Tree{Root: root, Annotate: annotate}, nil
+}
+
+func (n *TreeNode) child(name string, dir bool) *TreeNode {
+	for _, c := range n.Children {
+		if c.Name == name && c.Dir == dir {
+			return c
+		}
+	}
+	c := &TreeNode{Name: name, Dir: dir}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// String renders the tree as the tree command does, one entry per line.
+func (t *Tree) String() string {
+	var b strings.Builder
+	b.WriteString(".\n")
+	t.render(&b, t.Root, "")
+	return b.String()
+}
+
+func (t *Tree) render(b *strings.Builder, node *TreeNode, indent string) {
+	for i, c := range node.Children {
+		branch, next := "├── ", "│   "
+		if i == len(node.Children)-1 {
+			branch, next = "└── ", "    "
+		}
+
+		b.WriteString(indent + branch + c.Name)
+		if c.Dir {
+			b.WriteString("/")
+		} else if label := t.label(c); label != "" {
+			b.WriteString(" (" + label + ")")
+		}
+		b.WriteByte('\n')
+
+		if c.Dir {
+			t.render(b, c, indent+next)
+		}
+	}
+}
+
+// label returns the annotation shown next to a file.
+func (t *Tree) label(n *TreeNode) string {
+	switch t.Annotate {
+	case TreeSize:
+		return formatSize(n.Size)
+	case TreeLines:
+		return formatLines(n.Lines)
+	}
+	return ""
+}
+
+func formatLines(n int) string {
+	if n == 1 {
+		return "1 line"
+	}
+	return fmt.Sprintf("%d lines", n)
+}
+
+// formatSize renders a byte count in powers of 1024.
+func formatSize(n int64) string {
+	const unit = 1 << 10
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "K"
+	for _, s := range []string{"M", "G"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, s
+	}
+	return fmt.Sprintf("%.1f %sB", value, suffix)
+}
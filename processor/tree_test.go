@@ -0,0 +1,80 @@
+package processor
+
+import "testing"
+
+func TestNewTree(t *testing.T) {
+	files := []FileInfo{
+		{RelPath: "README.md", Size: 10, Lines: 1},
+		{RelPath: "cmd/root.go", Size: 27, Lines: 3},
+		{RelPath: "cmd/sub/x.go", Size: 1536, Lines: 1},
+		{RelPath: "internal/a/b.go", Size: 9, Lines: 1},
+	}
+
+	tests := []struct {
+		annotate string
+		want     string
+	}{
+		{TreePlain, `.
+├── README.md
+├── cmd/
+│   ├── root.go
+│   └── sub/
+│       └── x.go
+└── internal/
+    └── a/
+        └── b.go
+`},
+		{TreeSize, `.
+├── README.md (10 B)
+├── cmd/
+│   ├── root.go (27 B)
+│   └── sub/
+│       └── x.go (1.5 KB)
+└── internal/
+    └── a/
+        └── b.go (9 B)
+`},
+		{TreeLines, `.
+├── README.md (1 line)
+├── cmd/
+│   ├── root.go (3 lines)
+│   └── sub/
+│       └── x.go (1 line)
+└── internal/
+    └── a/
+        └── b.go (1 line)
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.annotate, func(t *testing.T) {
+			tree, err := NewTree(files, tt.annotate)
+			if err != nil {
+				t.Fatalf("NewTree failed: %v", err)
+			}
+			if got := tree.String(); got != tt.want {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := NewTree(files, "bogus"); err == nil {
+		t.Error("expected error for unknown annotation")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:             "0 B",
+		1023:          "1023 B",
+		1024:          "1.0 KB",
+		5 << 20:       "5.0 MB",
+		3 << 30:       "3.0 GB",
+		1<<20 + 1<<19: "1.5 MB",
+	}
+	for n, want := range tests {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d): expected %q, got %q", n, want, got)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"iter"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type XMLProcessor struct{}
+
+func NewXMLProcessor() *XMLProcessor {
+	return &XMLProcessor{}
+}
+
+func (x *XMLProcessor) Name() string {
+	return "xml"
+}
+
+func (x *XMLProcessor) FileExtension() string {
+	return ".xml"
+}
+
+func (x *XMLProcessor) Process(files []FileInfo, opts Options) ([]byte, error) {
+	var out bytes.Buffer
+	if err := x.ProcessTo(&out, slices.Values(files), opts); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// ProcessTo writes the documents to w one file at a time. A directory tree
+// needs every file up front, so with opts.Tree the files are collected
+// first.
+func (x *XMLProcessor) ProcessTo(w io.Writer, files iter.Seq[FileInfo], opts Options) error {
+	out := bufio.NewWriter(w)
+
+	out.WriteString("<documents>\n")
+
+	if opts.Part != nil {
+		fmt.Fprintf(out, "<part index=\"%d\" total=\"%d\">\n", opts.Part.Index, opts.Part.Total)
+		for _, f := range opts.Part.Files {
+			if f.StartLine > 0 {
+				fmt.Fprintf(out, "<file start_line=\"%d\" end_line=\"%d\">", f.StartLine, f.EndLine)
+			} else {
+				out.WriteString("<file>")
+			}
+			if err := xml.EscapeText(out, []byte(filepath.ToSlash(f.RelPath))); err != nil {
+				return err
+			}
+			out.WriteString("</file>\n")
+		}
+		out.WriteString("</part>\n")
+	}
+
+	if opts.Tree {
+		collected := slices.Collect(files)
+		tree, err := NewTree(collected, opts.TreeAnnotate)
+		if err != nil {
+			return err
+		}
+		out.WriteString("<directory_tree>\n")
+		for _, c := range tree.Root.Children {
+			if err := writeXMLTree(out, c, tree.Annotate); err != nil {
+				return err
+			}
+		}
+		out.WriteString("</directory_tree>\n")
+		files = slices.Values(collected)
+	}
+
+	i := 0
+	for file := range files {
+		i++
+		fmt.Fprintf(out, "<document index=\"%d\">\n", i)
+
+		out.WriteString("<source>")
+		if err := xml.EscapeText(out, []byte(filepath.ToSlash(file.RelPath))); err != nil {
+			return err
+		}
+		out.WriteString("</source>\n")
+		if hasMeta(file, opts) {
+			writeXMLMeta(out, file)
+		}
+
+		// Content without a final newline is closed on its last line, so
+		// that extraction gives it back as it was.
+		out.WriteString("<document_content>\n")
+		writeXMLContent(out, file.Content)
+		out.WriteString("</document_content>\n")
+
+		out.WriteString("</document>\n")
+	}
+
+	if len(opts.Deleted) > 0 {
+		out.WriteString("<deleted_files>\n")
+		for _, path := range opts.Deleted {
+			out.WriteString("<file>")
+			if err := xml.EscapeText(out, []byte(filepath.ToSlash(path))); err != nil {
+				return err
+			}
+			out.WriteString("</file>\n")
+		}
+		out.WriteString("</deleted_files>\n")
+	}
+
+	if len(opts.Omitted) > 0 || opts.Unlisted > 0 {
+		if opts.Unlisted > 0 {
+			fmt.Fprintf(out, "<omitted_files unlisted=\"%d\">\n", opts.Unlisted)
+		} else {
+			out.WriteString("<omitted_files>\n")
+		}
+		for _, o := range opts.Omitted {
+			fmt.Fprintf(out, "<file tokens=\"%d\" truncated=\"%t\">", o.Tokens, o.Truncated)
+			if err := xml.EscapeText(out, []byte(filepath.ToSlash(o.RelPath))); err != nil {
+				return err
+			}
+			out.WriteString("</file>\n")
+		}
+		out.WriteString("</omitted_files>\n")
+	}
+
+	out.WriteString("</documents>\n")
+
+	return out.Flush()
+}
+
+func writeXMLMeta(out textWriter, file FileInfo) {
+	fmt.Fprintf(out, "<metadata size=\"%d\" lines=\"%d\"", file.Size, file.Lines)
+	if !file.ModTime.IsZero() {
+		fmt.Fprintf(out, " modified=\"%s\"", file.ModTime.Format(time.RFC3339))
+	}
+	if file.Mode != 0 {
+		fmt.Fprintf(out, " mode=\"%s\"", file.Mode)
+	}
+	fmt.Fprintf(out, " sha256=\"%s\"/>\n", file.SHA256)
+}
+
+func writeXMLTree(out textWriter, node *TreeNode, annotate string) error {
+	if node.Dir {
+		out.WriteString("<directory name=\"")
+	} else {
+		out.WriteString("<file name=\"")
+	}
+	if err := xml.EscapeText(out, []byte(node.Name)); err != nil {
+		return err
+	}
+	out.WriteString("\"")
+
+	if !node.Dir {
+		switch annotate {
+		case TreeSize:
+			fmt.Fprintf(out, " size=\"%d\"", node.Size)
+		case TreeLines:
+			fmt.Fprintf(out, " lines=\"%d\"", node.Lines)
+		}
+		out.WriteString("/>\n")
+		return nil
+	}
+
+	out.WriteString(">\n")
+	for _, c := range node.Children {
+		if err := writeXMLTree(out, c, annotate); err != nil {
+			return err
+		}
+	}
+	out.WriteString("</directory>\n")
+	return nil
+}
+
+// writeXMLContent writes content verbatim when it contains no markup and
+// wraps it in CDATA sections otherwise. Carriage returns are written as
+// character references, outside CDATA, because XML parsers turn a literal
+// one into a newline. Characters that XML 1.0 cannot represent at all are
+// replaced with U+FFFD.
+func writeXMLContent(out textWriter, content []byte) {
+	content = sanitizeXMLChars(content)
+
+	if !bytes.ContainsAny(content, "<&") && !bytes.Contains(content, []byte("]]>")) {
+		out.Write(bytes.ReplaceAll(content, []byte("\r"), []byte("&#xD;")))
+		return
+	}
+
+	content = bytes.ReplaceAll(content, []byte("]]>"), []byte("]]]]><![CDATA[>"))
+	content = bytes.ReplaceAll(content, []byte("\r"), []byte("]]>&#xD;<![CDATA["))
+	out.WriteString("<![CDATA[")
+	out.Write(content)
+	out.WriteString("]]>")
+}
+
+func sanitizeXMLChars(content []byte) []byte {
+	clean := true
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		if (r == utf8.RuneError && size == 1) || !isXMLChar(r) {
+			clean = false
+			break
+		}
+		i += size
+	}
+	if clean {
+		return content
+	}
+
+	out := make([]byte, 0, len(content))
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		if (r == utf8.RuneError && size == 1) || !isXMLChar(r) {
+			r = utf8.RuneError
+		}
+		out = utf8.AppendRune(out, r)
+		i += size
+	}
+	return out
+}
+
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= 0x10FFFF)
+}
+
+func (x *XMLProcessor) Extract(data []byte) ([]FileInfo, error) {
+	var doc struct {
+		Documents []struct {
+			Source  string `xml:"source"`
+			Content string `xml:"document_content"`
+		} `xml:"document"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing xml bundle: %w", err)
+	}
+
+	files := make([]FileInfo, 0, len(doc.Documents))
+	for _, d := range doc.Documents {
+		content := strings.TrimPrefix(d.Content, "\n")
+		files = append(files, extractedFile(d.Source, []byte(content)))
+	}
+	return files, nil
+}
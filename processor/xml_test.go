@@ -0,0 +1,121 @@
+package processor
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+type xmlDocuments struct {
+	Documents []struct {
+		Index   int    `xml:"index,attr"`
+		Source  string `xml:"source"`
+		Content string `xml:"document_content"`
+	} `xml:"document"`
+}
+
+func TestXMLProcessor(t *testing.T) {
+	proc := NewXMLProcessor()
+
+	t.Run("Name and extension", func(t *testing.T) {
+		if proc.Name() != "xml" {
+			t.Errorf("expected name 'xml', got '%s'", proc.Name())
+		}
+		if proc.FileExtension() != ".xml" {
+			t.Errorf("expected extension '.xml', got '%s'", proc.FileExtension())
+		}
+	})
+
+	t.Run("Empty files", func(t *testing.T) {
+		result, err := proc.Process([]FileInfo{}, Options{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if string(result) != "<documents>\n</documents>\n" {
+			t.Errorf("unexpected output: %q", result)
+		}
+	})
+
+	t.Run("Document layout", func(t *testing.T) {
+		files := []FileInfo{
+			{RelPath: "main.go", Content: []byte("package main\n"), Ext: ".go"},
+			{RelPath: "cmd/root.go", Content: []byte("package cmd"), Ext: ".go"},
+		}
+
+		result, err := proc.Process(files, Options{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		expected := `<documents>
+<document index="1">
+<source>main.go</source>
+<document_content>
+package main
+</document_content>
+</document>
+<document index="2">
+<source>cmd/root.go</source>
+<document_content>
+package cmd</document_content>
+</document>
+</documents>
+`
+		if string(result) != expected {
+			t.Errorf("unexpected output:\n%s", result)
+		}
+	})
+
+	t.Run("Markup is CDATA wrapped and round-trips", func(t *testing.T) {
+		content := "if a < b && c > d {\n\tx := \"]]>\"\n}\n"
+		files := []FileInfo{
+			{RelPath: "a&b.go", Content: []byte(content), Ext: ".go"},
+		}
+
+		result, err := proc.Process(files, Options{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !strings.Contains(string(result), "<![CDATA[") {
+			t.Error("expected CDATA section")
+		}
+
+		var docs xmlDocuments
+		if err := xml.Unmarshal(result, &docs); err != nil {
+			t.Fatalf("output is not well-formed XML: %v", err)
+		}
+		if len(docs.Documents) != 1 {
+			t.Fatalf("expected 1 document, got %d", len(docs.Documents))
+		}
+
+		doc := docs.Documents[0]
+		if doc.Index != 1 {
+			t.Errorf("expected index 1, got %d", doc.Index)
+		}
+		if doc.Source != "a&b.go" {
+			t.Errorf("expected source 'a&b.go', got '%s'", doc.Source)
+		}
+		if doc.Content != "\n"+content {
+			t.Errorf("content mismatch: %q", doc.Content)
+		}
+	})
+
+	t.Run("Invalid XML characters are replaced", func(t *testing.T) {
+		files := []FileInfo{
+			{RelPath: "bin.dat", Content: []byte("a\x00b\xffc\n"), Ext: ".dat"},
+		}
+
+		result, err := proc.Process(files, Options{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		var docs xmlDocuments
+		if err := xml.Unmarshal(result, &docs); err != nil {
+			t.Fatalf("output is not well-formed XML: %v", err)
+		}
+		if docs.Documents[0].Content != "\na�b�c\n" {
+			t.Errorf("unexpected content: %q", docs.Documents[0].Content)
+		}
+	})
+}
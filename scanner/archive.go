@@ -0,0 +1,227 @@
+package scanner
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var archiveSuffixes = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// IsArchive reports whether path names an archive OpenArchive can read.
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenArchive opens a .zip, .tar, .tar.gz or .tgz file as a read-only file
+// system. Zip files are read in place; tar files are streamed once and held
+// in memory, since they cannot be accessed randomly. The returned closer
+// must be closed once the file system is no longer needed.
+func OpenArchive(name string) (fs.FS, io.Closer, error) {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".zip") {
+		r, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening zip archive: %w", err)
+		}
+		return r, r, nil
+	}
+
+	if !IsArchive(name) {
+		return nil, nil, fmt.Errorf("%s: unsupported archive type", name)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening gzip stream: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	fsys, err := readTar(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading tar archive: %w", err)
+	}
+	return fsys, nopCloser{}, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+func readTar(r io.Reader) (*memFS, error) {
+	fsys := newMemFS()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fsys, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		if name == "" {
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if _, err := fsys.mkdirAll(name, hdr.ModTime); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			if err := fsys.add(name, data, hdr.FileInfo().Mode().Perm(), hdr.ModTime); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// memFS is a minimal read-only in-memory file system. Parent directories
+// are created implicitly.
+type memFS struct {
+	nodes map[string]*memNode
+}
+
+type memNode struct {
+	name     string
+	data     []byte
+	mode     fs.FileMode
+	modTime  time.Time
+	children []*memNode
+}
+
+func newMemFS() *memFS {
+	root := &memNode{name: ".", mode: fs.ModeDir | 0o755}
+	return &memFS{nodes: map[string]*memNode{".": root}}
+}
+
+func (m *memFS) mkdirAll(name string, modTime time.Time) (*memNode, error) {
+	if n, ok := m.nodes[name]; ok {
+		if !n.mode.IsDir() {
+			return nil, fmt.Errorf("%s: is both a file and a directory in the archive", name)
+		}
+		return n, nil
+	}
+	parent, err := m.mkdirAll(path.Dir(name), modTime)
+	if err != nil {
+		return nil, err
+	}
+	n := &memNode{name: path.Base(name), mode: fs.ModeDir | 0o755, modTime: modTime}
+	parent.children = append(parent.children, n)
+	m.nodes[name] = n
+	return n, nil
+}
+
+// add stores a file, replacing any earlier entry of the same name as tar
+// does on extraction.
+func (m *memFS) add(name string, data []byte, perm fs.FileMode, modTime time.Time) error {
+	if n, ok := m.nodes[name]; ok {
+		if n.mode.IsDir() {
+			return fmt.Errorf("%s: is both a file and a directory in the archive", name)
+		}
+		n.data, n.mode, n.modTime = data, perm, modTime
+		return nil
+	}
+	parent, err := m.mkdirAll(path.Dir(name), modTime)
+	if err != nil {
+		return err
+	}
+	n := &memNode{name: path.Base(name), data: data, mode: perm, modTime: modTime}
+	parent.children = append(parent.children, n)
+	m.nodes[name] = n
+	return nil
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	n, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if n.mode.IsDir() {
+		return &memDir{node: n}, nil
+	}
+	return &memFile{node: n, r: bytes.NewReader(n.data)}, nil
+}
+
+func (n *memNode) Name() string       { return n.name }
+func (n *memNode) Size() int64        { return int64(len(n.data)) }
+func (n *memNode) Mode() fs.FileMode  { return n.mode }
+func (n *memNode) ModTime() time.Time { return n.modTime }
+func (n *memNode) IsDir() bool        { return n.mode.IsDir() }
+func (n *memNode) Sys() any           { return nil }
+
+type memFile struct {
+	node *memNode
+	r    *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.node, nil }
+func (f *memFile) Read(p []byte) (int, error) { return f.r.Read(p) }
+func (f *memFile) Close() error               { return nil }
+
+type memDir struct {
+	node   *memNode
+	offset int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.node, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.name, Err: errors.New("is a directory")}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	children := append([]*memNode(nil), d.node.children...)
+	sort.Slice(children, func(i, j int) bool { return children[i].name < children[j].name })
+
+	rest := children[d.offset:]
+	if n > 0 {
+		if len(rest) == 0 {
+			return nil, io.EOF
+		}
+		rest = rest[:min(n, len(rest))]
+	}
+	d.offset += len(rest)
+
+	entries := make([]fs.DirEntry, len(rest))
+	for i, c := range rest {
+		entries[i] = fs.FileInfoToDirEntry(c)
+	}
+	return entries, nil
+}
@@ -0,0 +1,187 @@
+package scanner
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+var archiveFiles = map[string]string{
+	"main.go":        "package main\n",
+	"pkg/util.go":    "package pkg\n",
+	"pkg/deep/x.go":  "package deep\n",
+	"docs/readme.md": "# docs\n",
+}
+
+func writeZip(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range archiveFiles {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "./pkg/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range archiveFiles {
+		hdr := &tar.Header{Name: "./" + name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	writers := map[string]func(*testing.T, string){
+		"src.zip":    writeZip,
+		"src.tar.gz": writeTarGz,
+	}
+
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, name)
+			write(t, path)
+
+			if !IsArchive(path) {
+				t.Fatalf("expected %s to be recognised as an archive", name)
+			}
+
+			fsys, closer, err := OpenArchive(path)
+			if err != nil {
+				t.Fatalf("failed to open archive: %v", err)
+			}
+			defer closer.Close()
+
+			if err := fstest.TestFS(fsys, "main.go", "pkg/util.go", "pkg/deep/x.go", "docs/readme.md"); err != nil {
+				t.Fatal(err)
+			}
+
+			content, err := fs.ReadFile(fsys, "pkg/util.go")
+			if err != nil || string(content) != "package pkg\n" {
+				t.Errorf("unexpected content %q (%v)", content, err)
+			}
+
+			results, err := NewFS(fsys, path, &extensionOnlyFilter{ext: ".go"}).Scan()
+			if err != nil {
+				t.Fatalf("scan failed: %v", err)
+			}
+			if len(results) != 3 {
+				t.Errorf("expected 3 files, got %v", results)
+			}
+		})
+	}
+}
+
+func TestOpenArchive_Conflicts(t *testing.T) {
+	file := func(name string) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: 1}
+	}
+	dir := func(name string) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755}
+	}
+
+	tests := []struct {
+		name    string
+		entries []*tar.Header
+	}{
+		{"file then child", []*tar.Header{file("a"), file("a/b")}},
+		{"file then nested child", []*tar.Header{file("a"), file("a/b/c")}},
+		{"file then directory", []*tar.Header{file("a"), dir("a/")}},
+		{"child then file", []*tar.Header{file("a/b"), file("a")}},
+		{"directory then file", []*tar.Header{dir("a/"), file("a")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "src.tar")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tw := tar.NewWriter(f)
+			for _, hdr := range tt.entries {
+				if err := tw.WriteHeader(hdr); err != nil {
+					t.Fatal(err)
+				}
+				if hdr.Typeflag == tar.TypeReg {
+					if _, err := io.WriteString(tw, "x"); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			if _, _, err := OpenArchive(path); err == nil {
+				t.Error("expected error for conflicting entries")
+			}
+		})
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"src.zip", true},
+		{"src.TAR", true},
+		{"src.tar.gz", true},
+		{"src.tgz", true},
+		{"src", false},
+		{"main.go", false},
+		{"archive.gz", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if result := IsArchive(tt.path); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
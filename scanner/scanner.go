@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
-	"path/filepath"
 	"sort"
 
 	"amalgo/filter"
@@ -12,12 +11,23 @@ import (
 
 type Scanner struct {
 	baseDir string
+	source  Source
 	filter  filter.Filter
 }
 
 func New(baseDir string, f filter.Filter) *Scanner {
+	return NewWithSource(baseDir, DirSource(baseDir), f)
+}
+
+// NewFS scans fsys, reporting paths as if it were mounted at baseDir.
+func NewFS(fsys fs.FS, baseDir string, f filter.Filter) *Scanner {
+	return NewWithSource(baseDir, FSSource(fsys, baseDir), f)
+}
+
+func NewWithSource(baseDir string, src Source, f filter.Filter) *Scanner {
 	return &Scanner{
 		baseDir: baseDir,
+		source:  src,
 		filter:  f,
 	}
 }
@@ -25,7 +35,7 @@ func New(baseDir string, f filter.Filter) *Scanner {
 func (s *Scanner) Scan() ([]string, error) {
 	var files []string
 
-	err := filepath.WalkDir(s.baseDir, func(path string, d fs.DirEntry, walkErr error) error {
+	err := s.source.Walk(func(path string, d fs.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			fmt.Fprintf(os.Stderr, "warn: skipping %s: %v\n", path, walkErr)
 			return nil
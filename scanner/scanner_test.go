@@ -5,6 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
+
+	"amalgo/filter"
 )
 
 func TestScanner(t *testing.T) {
@@ -165,6 +168,84 @@ func TestScanner(t *testing.T) {
 	})
 }
 
+func TestPathSource(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, f := range []string{"main.go", "untracked.go", "ignore/skip.go", "pkg/util.go"} {
+		fullPath := filepath.Join(tmpDir, f)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("package main"), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	t.Run("Only listed files", func(t *testing.T) {
+		src := PathSource(tmpDir, []string{"pkg/util.go", "main.go", "ignore/skip.go"})
+		results, err := NewWithSource(tmpDir, src, &allowAllFilter{}).Scan()
+		if err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+
+		expected := []string{"ignore/skip.go", "main.go", "pkg/util.go"}
+		if len(results) != len(expected) {
+			t.Fatalf("expected %d files, got %d", len(expected), len(results))
+		}
+		for i, f := range results {
+			if rel := filepath.ToSlash(filter.RelPath(f, tmpDir)); rel != expected[i] {
+				t.Errorf("expected %s at position %d, got %s", expected[i], i, rel)
+			}
+		}
+	})
+
+	t.Run("Directory filters apply", func(t *testing.T) {
+		src := PathSource(tmpDir, []string{"main.go", "ignore/skip.go"})
+		results, err := NewWithSource(tmpDir, src, &skipDirFilter{skipDir: "ignore"}).Scan()
+		if err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		if len(results) != 1 || filepath.Base(results[0]) != "main.go" {
+			t.Errorf("expected only main.go, got %v", results)
+		}
+	})
+
+	t.Run("Missing files are skipped", func(t *testing.T) {
+		src := PathSource(tmpDir, []string{"main.go", "deleted.go"})
+		results, err := NewWithSource(tmpDir, src, &allowAllFilter{}).Scan()
+		if err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		if len(results) != 1 {
+			t.Errorf("expected 1 file, got %d", len(results))
+		}
+	})
+}
+
+func TestFSSource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":        {Data: []byte("package main")},
+		"ignore/skip.go": {Data: []byte("package ignore")},
+		"pkg/util.go":    {Data: []byte("package pkg")},
+	}
+
+	base := filepath.Join("virtual", "root")
+	results, err := NewWithSource(base, FSSource(fsys, base), &skipDirFilter{skipDir: "ignore"}).Scan()
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+
+	expected := []string{filepath.Join(base, "main.go"), filepath.Join(base, "pkg", "util.go")}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d files, got %d", len(expected), len(results))
+	}
+	for i, f := range results {
+		if f != expected[i] {
+			t.Errorf("expected %s at position %d, got %s", expected[i], i, f)
+		}
+	}
+}
+
 type allowAllFilter struct{}
 
 func (f *allowAllFilter) ShouldInclude(path string, d fs.DirEntry) bool {
@@ -0,0 +1,114 @@
+package scanner
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Source produces the entries a Scanner visits. Walk follows the
+// fs.WalkDir contract: directories come before their contents, and
+// returning fs.SkipDir for a directory skips everything below it.
+type Source interface {
+	Walk(fn fs.WalkDirFunc) error
+}
+
+// DirSource walks the directory tree rooted at dir on disk.
+func DirSource(dir string) Source {
+	return FSSource(os.DirFS(dir), dir)
+}
+
+type fsSource struct {
+	fsys    fs.FS
+	baseDir string
+}
+
+// FSSource walks fsys, reporting each entry as if fsys were mounted at
+// baseDir so that filters see the same paths as for a directory on disk.
+func FSSource(fsys fs.FS, baseDir string) Source {
+	return &fsSource{fsys: fsys, baseDir: baseDir}
+}
+
+func (s *fsSource) Walk(fn fs.WalkDirFunc) error {
+	return fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		return fn(filepath.Join(s.baseDir, filepath.FromSlash(name)), d, err)
+	})
+}
+
+type pathSource struct {
+	fsys    fs.FS
+	baseDir string
+	paths   []string
+}
+
+// PathSource visits a fixed set of files on disk, given as slash-separated
+// paths relative to baseDir. Their parent directories are visited too, so
+// that directory filters apply as they would during a real walk.
+func PathSource(baseDir string, relPaths []string) Source {
+	return FSPathSource(os.DirFS(baseDir), baseDir, relPaths)
+}
+
+// FSPathSource is PathSource for files looked up in fsys, mounted at
+// baseDir as with FSSource.
+func FSPathSource(fsys fs.FS, baseDir string, relPaths []string) Source {
+	paths := append([]string(nil), relPaths...)
+	sort.Strings(paths)
+	return &pathSource{fsys: fsys, baseDir: baseDir, paths: paths}
+}
+
+func (p *pathSource) Walk(fn fs.WalkDirFunc) error {
+	descend := make(map[string]bool)
+
+	visitDir := func(rel string) (bool, error) {
+		if ok, seen := descend[rel]; seen {
+			return ok, nil
+		}
+		err := p.visit(rel, fn)
+		if errors.Is(err, fs.SkipDir) {
+			descend[rel] = false
+			return false, nil
+		}
+		descend[rel] = err == nil
+		return err == nil, err
+	}
+
+	if ok, err := visitDir("."); !ok {
+		return err
+	}
+
+	for _, rel := range p.paths {
+		ok := true
+		parts := strings.Split(rel, "/")
+		for i := 1; i < len(parts) && ok; i++ {
+			var err error
+			if ok, err = visitDir(path.Join(parts[:i]...)); err != nil {
+				return err
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if err := p.visit(rel, fn); err != nil && !errors.Is(err, fs.SkipDir) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *pathSource) visit(rel string, fn fs.WalkDirFunc) error {
+	full := p.baseDir
+	if rel != "." {
+		full = filepath.Join(p.baseDir, filepath.FromSlash(rel))
+	}
+
+	info, err := fs.Stat(p.fsys, rel)
+	if err != nil {
+		return fn(full, nil, err)
+	}
+	return fn(full, fs.FileInfoToDirEntry(info), nil)
+}
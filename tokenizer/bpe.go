@@ -0,0 +1,218 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+//go:generate go run gen_vocab.go -out vocab.tiktoken -size 8192 $GOROOT/src
+
+// vocab is a byte-level rank table in the tiktoken file format, trained
+// with gen_vocab.go on the Go source tree using the cl100k pre-tokenisation.
+// It is not cl100k_base: its counts are a rough approximation, closest for
+// Go code and further off for other text. Load the official
+// cl100k_base.tiktoken with LoadBPE for real counts.
+//
+//go:embed vocab.tiktoken
+var vocab []byte
+
+var (
+	embeddedBPE     *BPE
+	embeddedBPEErr  error
+	embeddedBPEOnce sync.Once
+)
+
+// EmbeddedBPEName names the encoder built from the embedded vocabulary, so
+// that reports do not pass its counts off as those of a real model.
+const EmbeddedBPEName = "approx-go"
+
+const maxCacheEntries = 1 << 16
+
+type BPE struct {
+	name  string
+	ranks map[string]int
+
+	mu    sync.Mutex
+	cache map[string]int
+}
+
+func EmbeddedBPE() (*BPE, error) {
+	embeddedBPEOnce.Do(func() {
+		embeddedBPE, embeddedBPEErr = LoadBPE(EmbeddedBPEName, bytes.NewReader(vocab))
+	})
+	return embeddedBPE, embeddedBPEErr
+}
+
+// LoadBPE reads a rank table in the tiktoken format: one base64-encoded
+// token and its rank per line.
+func LoadBPE(name string, r io.Reader) (*BPE, error) {
+	ranks := make(map[string]int)
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		fields := bytes.Fields(sc.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("vocabulary line %d: expected token and rank", line)
+		}
+
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("vocabulary line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("vocabulary line %d: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("vocabulary is missing single byte 0x%02x", b)
+		}
+	}
+
+	return &BPE{
+		name:  name,
+		ranks: ranks,
+		cache: make(map[string]int),
+	}, nil
+}
+
+func (b *BPE) Name() string {
+	return b.name
+}
+
+func (b *BPE) Count(text []byte) int {
+	total := 0
+	for _, piece := range Pretokenize(text) {
+		total += b.countPiece(piece)
+	}
+	return total
+}
+
+func (b *BPE) countPiece(piece []byte) int {
+	if _, ok := b.ranks[string(piece)]; ok {
+		return 1
+	}
+
+	key := string(piece)
+	b.mu.Lock()
+	n, ok := b.cache[key]
+	b.mu.Unlock()
+	if ok {
+		return n
+	}
+
+	n = len(b.merge(piece))
+
+	b.mu.Lock()
+	if len(b.cache) >= maxCacheEntries {
+		clear(b.cache)
+	}
+	b.cache[key] = n
+	b.mu.Unlock()
+
+	return n
+}
+
+// merge applies the lowest-ranked adjacent merge, leftmost first, until
+// none remain and returns the start offsets of the resulting tokens. The
+// parts are kept as a linked list of start offsets and the candidate merges
+// in a heap, so a piece of n bytes takes O(n log n) rather than rescanning
+// it after every merge. Candidates made stale by an earlier merge are
+// skipped when they come off the heap.
+func (b *BPE) merge(piece []byte) []int {
+	n := len(piece)
+	if n == 0 {
+		return nil
+	}
+
+	// next[i] is the start of the part after the one starting at i, n for
+	// the last part and -1 once i has been merged into its left neighbour.
+	next := make([]int, n)
+	prev := make([]int, n)
+	for i := range n {
+		next[i], prev[i] = i+1, i-1
+	}
+
+	var candidates mergeHeap
+	candidate := func(start int) (mergeCandidate, bool) {
+		mid := next[start]
+		if mid >= n {
+			return mergeCandidate{}, false
+		}
+		end := next[mid]
+		rank, ok := b.ranks[string(piece[start:end])]
+		return mergeCandidate{rank: rank, start: start, mid: mid, end: end}, ok
+	}
+	for i := range n {
+		if c, ok := candidate(i); ok {
+			candidates = append(candidates, c)
+		}
+	}
+	heap.Init(&candidates)
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(&candidates).(mergeCandidate)
+		if next[c.start] != c.mid || next[c.mid] != c.end {
+			continue
+		}
+
+		next[c.start], next[c.mid] = c.end, -1
+		if c.end < n {
+			prev[c.end] = c.start
+		}
+		for _, start := range []int{prev[c.start], c.start} {
+			if start < 0 {
+				continue
+			}
+			if c, ok := candidate(start); ok {
+				heap.Push(&candidates, c)
+			}
+		}
+	}
+
+	var starts []int
+	for i := 0; i < n; i = next[i] {
+		starts = append(starts, i)
+	}
+	return starts
+}
+
+type mergeCandidate struct {
+	rank, start, mid, end int
+}
+
+// mergeHeap orders candidates by rank, then by position, matching the
+// order in which tiktoken applies merges.
+type mergeHeap []mergeCandidate
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].start < h[j].start
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(mergeCandidate)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
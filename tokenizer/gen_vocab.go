@@ -0,0 +1,216 @@
+//go:build ignore
+
+// gen_vocab trains the byte-level BPE rank table embedded by bpe.go. It
+// reads source files under the given directories, splits them with the
+// cl100k pre-tokenisation and greedily merges the most frequent adjacent
+// token pair until the vocabulary reaches the requested size.
+//
+//	go run gen_vocab.go -out vocab.tiktoken -size 8192 $GOROOT/src
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"amalgo/tokenizer"
+)
+
+var corpusExts = map[string]struct{}{
+	".go": {}, ".py": {}, ".js": {}, ".ts": {}, ".c": {}, ".h": {},
+	".sh": {}, ".md": {}, ".txt": {}, ".html": {}, ".css": {}, ".json": {},
+	".yaml": {}, ".yml": {}, ".toml": {}, ".xml": {},
+}
+
+type pair [2]int
+
+type word struct {
+	syms []int
+	freq int
+}
+
+type entry struct {
+	p     pair
+	count int
+}
+
+type pairHeap []entry
+
+func (h pairHeap) Len() int { return len(h) }
+func (h pairHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count > h[j].count
+	}
+	if h[i].p[0] != h[j].p[0] {
+		return h[i].p[0] < h[j].p[0]
+	}
+	return h[i].p[1] < h[j].p[1]
+}
+func (h pairHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *pairHeap) Push(x any)   { *h = append(*h, x.(entry)) }
+func (h *pairHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+func main() {
+	out := flag.String("out", "vocab.tiktoken", "output rank table")
+	size := flag.Int("size", 8192, "vocabulary size including the 256 byte tokens")
+	maxBytes := flag.Int64("max-bytes", 64<<20, "maximum corpus size to read")
+	minFreq := flag.Int("min-freq", 2, "ignore pieces seen fewer times than this")
+	flag.Parse()
+
+	freqs := make(map[string]int)
+	var read int64
+	for _, root := range flag.Args() {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || read >= *maxBytes {
+				return nil
+			}
+			if d.IsDir() {
+				if d.Name() == "testdata" || d.Name() == "vendor" {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if _, ok := corpusExts[strings.ToLower(filepath.Ext(path))]; !ok {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			read += int64(len(data))
+			for _, piece := range tokenizer.Pretokenize(data) {
+				if len(piece) <= 64 {
+					freqs[string(piece)]++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	log.Printf("read %d bytes, %d distinct pieces", read, len(freqs))
+
+	keys := make([]string, 0, len(freqs))
+	for k, f := range freqs {
+		if f >= *minFreq && len(k) > 1 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	tokens := make([][]byte, 256)
+	for b := range tokens {
+		tokens[b] = []byte{byte(b)}
+	}
+
+	words := make([]word, len(keys))
+	counts := make(map[pair]int)
+	where := make(map[pair]map[int]struct{})
+	for wi, k := range keys {
+		syms := make([]int, len(k))
+		for i := 0; i < len(k); i++ {
+			syms[i] = int(k[i])
+		}
+		words[wi] = word{syms: syms, freq: freqs[k]}
+		for i := 0; i+1 < len(syms); i++ {
+			p := pair{syms[i], syms[i+1]}
+			counts[p] += freqs[k]
+			if where[p] == nil {
+				where[p] = make(map[int]struct{})
+			}
+			where[p][wi] = struct{}{}
+		}
+	}
+
+	h := &pairHeap{}
+	for p, c := range counts {
+		*h = append(*h, entry{p, c})
+	}
+	heap.Init(h)
+
+	for len(tokens) < *size && h.Len() > 0 {
+		e := heap.Pop(h).(entry)
+		if counts[e.p] != e.count || e.count == 0 {
+			continue
+		}
+
+		id := len(tokens)
+		tokens = append(tokens, append(append([]byte{}, tokens[e.p[0]]...), tokens[e.p[1]]...))
+
+		affected := make([]int, 0, len(where[e.p]))
+		for wi := range where[e.p] {
+			affected = append(affected, wi)
+		}
+		sort.Ints(affected)
+
+		changed := make(map[pair]struct{})
+		for _, wi := range affected {
+			w := &words[wi]
+			for i := 0; i+1 < len(w.syms); i++ {
+				p := pair{w.syms[i], w.syms[i+1]}
+				counts[p] -= w.freq
+				changed[p] = struct{}{}
+			}
+
+			merged := w.syms[:0:0]
+			for i := 0; i < len(w.syms); i++ {
+				if i+1 < len(w.syms) && w.syms[i] == e.p[0] && w.syms[i+1] == e.p[1] {
+					merged = append(merged, id)
+					i++
+					continue
+				}
+				merged = append(merged, w.syms[i])
+			}
+			w.syms = merged
+
+			for i := 0; i+1 < len(w.syms); i++ {
+				p := pair{w.syms[i], w.syms[i+1]}
+				counts[p] += w.freq
+				changed[p] = struct{}{}
+				if where[p] == nil {
+					where[p] = make(map[int]struct{})
+				}
+				where[p][wi] = struct{}{}
+			}
+		}
+		delete(where, e.p)
+
+		for p := range changed {
+			if c := counts[p]; c > 0 {
+				heap.Push(h, entry{p, c})
+			} else {
+				delete(counts, p)
+			}
+		}
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	for rank, tok := range tokens {
+		fmt.Fprintf(w, "%s %d\n", base64.StdEncoding.EncodeToString(tok), rank)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d tokens to %s", len(tokens), *out)
+}
@@ -0,0 +1,132 @@
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pretokenize splits text into the pieces that BPE merges operate on. It is
+// a hand-written equivalent of the cl100k_base pattern
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// which cannot be expressed with the regexp package because of the
+// negative lookahead.
+func Pretokenize(text []byte) [][]byte {
+	var pieces [][]byte
+	for i := 0; i < len(text); {
+		n := matchPiece(text[i:])
+		pieces = append(pieces, text[i:i+n])
+		i += n
+	}
+	return pieces
+}
+
+func matchPiece(s []byte) int {
+	if n := matchContraction(s); n > 0 {
+		return n
+	}
+
+	r0, w0 := utf8.DecodeRune(s)
+	r1, w1 := utf8.DecodeRune(s[w0:])
+	hasNext := w0 < len(s)
+
+	if unicode.IsLetter(r0) {
+		return w0 + runLength(s[w0:], unicode.IsLetter)
+	}
+	if hasNext && r0 != '\r' && r0 != '\n' && !unicode.IsNumber(r0) && unicode.IsLetter(r1) {
+		return w0 + w1 + runLength(s[w0+w1:], unicode.IsLetter)
+	}
+
+	if unicode.IsNumber(r0) {
+		n := w0
+		for count := 1; count < 3 && n < len(s); count++ {
+			r, w := utf8.DecodeRune(s[n:])
+			if !unicode.IsNumber(r) {
+				break
+			}
+			n += w
+		}
+		return n
+	}
+
+	start := 0
+	if r0 == ' ' && hasNext && isPunct(r1) {
+		start = w0
+	}
+	if isPunct(r0) || start > 0 {
+		n := start + runLength(s[start:], isPunct)
+		return n + runLength(s[n:], isNewline)
+	}
+
+	if unicode.IsSpace(r0) {
+		run := runLength(s, unicode.IsSpace)
+
+		lastNewline := -1
+		for i := 0; i < run; {
+			r, w := utf8.DecodeRune(s[i:])
+			if isNewline(r) {
+				lastNewline = i + w
+			}
+			i += w
+		}
+		if lastNewline > 0 {
+			return lastNewline
+		}
+
+		if run == len(s) {
+			return run
+		}
+		_, lastWidth := utf8.DecodeLastRune(s[:run])
+		if run > lastWidth {
+			return run - lastWidth
+		}
+		return run
+	}
+
+	return w0
+}
+
+func matchContraction(s []byte) int {
+	if len(s) < 2 || s[0] != '\'' {
+		return 0
+	}
+	lower := func(b byte) byte {
+		if b >= 'A' && b <= 'Z' {
+			return b + 'a' - 'A'
+		}
+		return b
+	}
+	switch lower(s[1]) {
+	case 's', 't', 'm', 'd':
+		return 2
+	}
+	if len(s) >= 3 {
+		switch string([]byte{lower(s[1]), lower(s[2])}) {
+		case "re", "ve", "ll":
+			return 3
+		}
+	}
+	return 0
+}
+
+func runLength(s []byte, pred func(rune) bool) int {
+	n := 0
+	for n < len(s) {
+		r, w := utf8.DecodeRune(s[n:])
+		if !pred(r) {
+			break
+		}
+		n += w
+	}
+	return n
+}
+
+func isPunct(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
@@ -0,0 +1,56 @@
+package tokenizer
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+type Encoder interface {
+	Name() string
+
+	Count(text []byte) int
+}
+
+// DefaultName names the encoder used when none is asked for. The embedded
+// BPE vocabulary is trained on Go code alone and overcounts prose, so it is
+// opt-in and the heuristic is the default.
+const DefaultName = "heuristic"
+
+var encoders = map[string]func() (Encoder, error){
+	EmbeddedBPEName: func() (Encoder, error) { return EmbeddedBPE() },
+	DefaultName:     func() (Encoder, error) { return NewHeuristic(), nil },
+}
+
+func Get(name string) (Encoder, error) {
+	newEncoder, ok := encoders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tokenizer: %s", name)
+	}
+	return newEncoder()
+}
+
+func List() []string {
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Heuristic estimates one token per four characters, which is close to
+// what BPE tokenizers produce for English text and typical source code.
+type Heuristic struct{}
+
+func NewHeuristic() *Heuristic {
+	return &Heuristic{}
+}
+
+func (h *Heuristic) Name() string {
+	return DefaultName
+}
+
+func (h *Heuristic) Count(text []byte) int {
+	return (utf8.RuneCount(text) + 3) / 4
+}
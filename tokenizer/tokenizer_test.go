@@ -0,0 +1,217 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPretokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"words", "hello world", []string{"hello", " world"}},
+		{"contractions", "I'm can't WE'LL", []string{"I", "'m", " can", "'t", " WE", "'LL"}},
+		{"numbers in groups of three", "12345", []string{"123", "45"}},
+		{"space before number", "x 42", []string{"x", " ", "42"}},
+		{"punctuation", "fmt.Println(x)", []string{"fmt", ".Println", "(x", ")"}},
+		{"space before punctuation", "a := b", []string{"a", " :=", " b"}},
+		{"newlines", "a\n\nb", []string{"a", "\n\n", "b"}},
+		{"indentation", "{\n\treturn\n}", []string{"{\n", "\treturn", "\n", "}"}},
+		{"trailing whitespace", "a   ", []string{"a", "   "}},
+		{"whitespace before word", "a   b", []string{"a", "  ", " b"}},
+		{"unicode letters", "héllo wörld", []string{"héllo", " wörld"}},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pieces := Pretokenize([]byte(tt.input))
+
+			var got []string
+			for _, p := range pieces {
+				got = append(got, string(p))
+			}
+
+			if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPretokenize_Lossless(t *testing.T) {
+	input := []byte("package main\r\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"héllo, 世界\", 3.14159)\n}\n\x00\xff")
+	joined := bytes.Join(Pretokenize(input), nil)
+	if !bytes.Equal(joined, input) {
+		t.Errorf("pieces do not reassemble input:\n%q\n%q", joined, input)
+	}
+}
+
+func testVocab(extra ...string) string {
+	var sb strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	for i, tok := range extra {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), 256+i)
+	}
+	return sb.String()
+}
+
+func TestBPE(t *testing.T) {
+	enc, err := LoadBPE("test", strings.NewReader(testVocab("ab", "abc", " ab", "cd")))
+	if err != nil {
+		t.Fatalf("failed to load vocabulary: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"", 0},
+		{"x", 1},
+		{"abc", 1},
+		{"abcd", 2},
+		{"abab", 2},
+		{" ab ab", 2},
+		{"xyz", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := enc.Count([]byte(tt.input)); got != tt.expected {
+				t.Errorf("expected %d tokens, got %d", tt.expected, got)
+			}
+			if got := enc.Count([]byte(tt.input)); got != tt.expected {
+				t.Errorf("cached count: expected %d tokens, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadBPE_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"missing byte tokens", "YQ== 0\n"},
+		{"bad base64", testVocab() + "!!! 256\n"},
+		{"bad rank", testVocab() + "YWI= x\n"},
+		{"wrong field count", testVocab() + "YWI=\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadBPE("test", strings.NewReader(tt.input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestEmbeddedBPE(t *testing.T) {
+	enc, err := EmbeddedBPE()
+	if err != nil {
+		t.Fatalf("embedded vocabulary failed to load: %v", err)
+	}
+
+	text := []byte("package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello, world\")\n}\n")
+	count := enc.Count(text)
+	if count <= 0 || count >= len(text) {
+		t.Errorf("expected token count between 1 and %d, got %d", len(text), count)
+	}
+}
+
+// naiveMerge is the textbook merge loop, rescanning the piece after every
+// merge, against which merge is checked.
+func naiveMerge(b *BPE, piece []byte) []int {
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+	for len(parts) > 2 {
+		best, bestRank := -1, 0
+		for i := 0; i+2 < len(parts); i++ {
+			if rank, ok := b.ranks[string(piece[parts[i]:parts[i+2]])]; ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return parts[:len(parts)-1]
+}
+
+func TestBPE_MergeOrder(t *testing.T) {
+	enc, err := EmbeddedBPE()
+	if err != nil {
+		t.Fatalf("embedded vocabulary failed to load: %v", err)
+	}
+
+	text := []byte("func (b *BPE) merge(piece []byte) []int {\n\treturn nil // aaaaaaaaaaaaaaaaaaaa\n}\n" +
+		"The quick brown fox jumps over the lazy dog. Zwölf Boxkämpfer jagen Viktor quer über den Sylter Deich.\n" +
+		strings.Repeat("ab", 300) + strings.Repeat("x", 257))
+	for _, piece := range Pretokenize(text) {
+		got, want := enc.merge(piece), naiveMerge(enc, piece)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%q: expected tokens at %v, got %v", piece, want, got)
+		}
+	}
+}
+
+func BenchmarkBPE_LongPiece(b *testing.B) {
+	enc, err := EmbeddedBPE()
+	if err != nil {
+		b.Fatalf("embedded vocabulary failed to load: %v", err)
+	}
+	piece := bytes.Repeat([]byte("qzj"), 50<<10/3)
+
+	b.SetBytes(int64(len(piece)))
+	for range b.N {
+		enc.merge(piece)
+	}
+}
+
+func TestHeuristic(t *testing.T) {
+	enc := NewHeuristic()
+
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"世界世界", 1},
+	}
+
+	for _, tt := range tests {
+		if got := enc.Count([]byte(tt.input)); got != tt.expected {
+			t.Errorf("%q: expected %d, got %d", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	for _, name := range List() {
+		enc, err := Get(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if enc.Name() != name {
+			t.Errorf("expected name '%s', got '%s'", name, enc.Name())
+		}
+	}
+
+	if _, err := Get("nonexistent"); err == nil {
+		t.Error("expected error for unknown tokenizer")
+	}
+}
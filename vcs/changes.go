@@ -0,0 +1,240 @@
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Changes lists the files under a directory that differ between two states
+// of a repository, as slash-separated paths relative to that directory.
+type Changes struct {
+	Modified []string
+	Deleted  []string
+}
+
+// ChangedSince compares the work tree, including uncommitted changes, with
+// rev. Untracked files are not included, as with git diff.
+func (r *Repo) ChangedSince(dir, rev string) (Changes, error) {
+	prefix, err := r.prefix(dir)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	commit, err := r.commit(rev)
+	if err != nil {
+		return Changes{}, err
+	}
+	base, err := commit.Tree()
+	if err != nil {
+		return Changes{}, fmt.Errorf("reading tree of %s: %w", rev, err)
+	}
+
+	candidates := make(map[string]struct{})
+
+	head, err := r.commit("HEAD")
+	if err != nil {
+		return Changes{}, err
+	}
+	headTree, err := head.Tree()
+	if err != nil {
+		return Changes{}, fmt.Errorf("reading tree of HEAD: %w", err)
+	}
+	diff, err := object.DiffTree(base, headTree)
+	if err != nil {
+		return Changes{}, fmt.Errorf("comparing %s with HEAD: %w", rev, err)
+	}
+	for _, c := range diff {
+		for _, name := range []string{c.From.Name, c.To.Name} {
+			if name != "" {
+				candidates[name] = struct{}{}
+			}
+		}
+	}
+
+	status, err := r.status()
+	if err != nil {
+		return Changes{}, err
+	}
+	for name, st := range status {
+		if st.Worktree == git.Untracked {
+			continue
+		}
+		if st.Staging != git.Unmodified || st.Worktree != git.Unmodified {
+			candidates[name] = struct{}{}
+			if st.Extra != "" {
+				candidates[st.Extra] = struct{}{}
+			}
+		}
+	}
+
+	var changes Changes
+	for name := range candidates {
+		rel, ok := trimPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+
+		var baseHash plumbing.Hash
+		entry, err := base.FindEntry(name)
+		if err == nil && entry.Mode.IsFile() {
+			baseHash = entry.Hash
+		}
+
+		hash, exists, err := r.worktreeHash(name)
+		if err != nil {
+			return Changes{}, err
+		}
+
+		switch {
+		case !exists && !baseHash.IsZero():
+			changes.Deleted = append(changes.Deleted, rel)
+		case exists && hash != baseHash:
+			changes.Modified = append(changes.Modified, rel)
+		}
+	}
+
+	changes.sort()
+	return changes, nil
+}
+
+// ChangedBetween compares the trees of two revisions.
+func (r *Repo) ChangedBetween(dir, from, to string) (Changes, error) {
+	prefix, err := r.prefix(dir)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	var trees [2]*object.Tree
+	for i, rev := range []string{from, to} {
+		commit, err := r.commit(rev)
+		if err != nil {
+			return Changes{}, err
+		}
+		if trees[i], err = commit.Tree(); err != nil {
+			return Changes{}, fmt.Errorf("reading tree of %s: %w", rev, err)
+		}
+	}
+
+	diff, err := object.DiffTree(trees[0], trees[1])
+	if err != nil {
+		return Changes{}, fmt.Errorf("comparing %s with %s: %w", from, to, err)
+	}
+
+	var changes Changes
+	for _, c := range diff {
+		if c.To.Name == "" {
+			if rel, ok := trimPrefix(c.From.Name, prefix); ok {
+				changes.Deleted = append(changes.Deleted, rel)
+			}
+			continue
+		}
+		if rel, ok := trimPrefix(c.To.Name, prefix); ok {
+			changes.Modified = append(changes.Modified, rel)
+		}
+	}
+
+	changes.sort()
+	return changes, nil
+}
+
+// Staged lists the changes recorded in the index relative to HEAD.
+func (r *Repo) Staged(dir string) (Changes, error) {
+	return r.statusChanges(dir, func(st *git.FileStatus) git.StatusCode { return st.Staging })
+}
+
+// Unstaged lists the changes in the work tree relative to the index.
+func (r *Repo) Unstaged(dir string) (Changes, error) {
+	return r.statusChanges(dir, func(st *git.FileStatus) git.StatusCode { return st.Worktree })
+}
+
+func (r *Repo) statusChanges(dir string, code func(*git.FileStatus) git.StatusCode) (Changes, error) {
+	prefix, err := r.prefix(dir)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	status, err := r.status()
+	if err != nil {
+		return Changes{}, err
+	}
+
+	var changes Changes
+	for name, st := range status {
+		rel, ok := trimPrefix(name, prefix)
+		switch code(st) {
+		case git.Unmodified, git.Untracked:
+			continue
+		case git.Deleted:
+			if ok {
+				changes.Deleted = append(changes.Deleted, rel)
+			}
+		case git.Renamed:
+			if old, oldOK := trimPrefix(st.Extra, prefix); oldOK && st.Extra != "" {
+				changes.Deleted = append(changes.Deleted, old)
+			}
+			fallthrough
+		default:
+			if ok {
+				changes.Modified = append(changes.Modified, rel)
+			}
+		}
+	}
+
+	changes.sort()
+	return changes, nil
+}
+
+func (r *Repo) status() (git.Status, error) {
+	wt, err := r.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("opening work tree: %w", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("reading work tree status: %w", err)
+	}
+	return status, nil
+}
+
+// worktreeHash returns the blob hash git would give the file name in the
+// work tree, and whether the file exists there at all.
+func (r *Repo) worktreeHash(name string) (plumbing.Hash, bool, error) {
+	path := filepath.Join(r.Root, filepath.FromSlash(name))
+
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return plumbing.ZeroHash, false, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, false, err
+	}
+	if info.IsDir() {
+		return plumbing.ZeroHash, false, nil
+	}
+
+	var content []byte
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return plumbing.ZeroHash, false, err
+		}
+		content = []byte(target)
+	} else if content, err = os.ReadFile(path); err != nil {
+		return plumbing.ZeroHash, false, err
+	}
+
+	return plumbing.ComputeHash(plumbing.BlobObject, content), true, nil
+}
+
+func (c *Changes) sort() {
+	sort.Strings(c.Modified)
+	sort.Strings(c.Deleted)
+}
@@ -0,0 +1,120 @@
+package vcs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestChanges(t *testing.T) {
+	dir := initRepo(t, map[string]string{
+		"main.go":       "package main\n",
+		"keep.go":       "package main\n",
+		"gone.go":       "package main\n",
+		"web/app.js":    "app\n",
+		"web/reset.js":  "reset\n",
+		"web/staged.js": "staged\n",
+	})
+	commitAll(t, dir, "base")
+
+	write := func(rel, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(rel)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("main.go", "package main\n\nfunc main() {}\n")
+	write("added.go", "package main\n")
+	if err := os.Remove(filepath.Join(dir, "gone.go")); err != nil {
+		t.Fatal(err)
+	}
+	write("web/reset.js", "changed\n")
+	commitAll(t, dir, "branch work")
+
+	write("web/reset.js", "reset\n")
+	write("web/staged.js", "staged change\n")
+	write("untracked.go", "package main\n")
+
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("web/staged.js"); err != nil {
+		t.Fatal(err)
+	}
+	write("web/app.js", "unstaged change\n")
+
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatalf("failed to open repository: %v", err)
+	}
+
+	t.Run("changed since", func(t *testing.T) {
+		changes, err := repo.ChangedSince(dir, "HEAD~1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := Changes{
+			Modified: []string{"added.go", "main.go", "web/app.js", "web/staged.js"},
+			Deleted:  []string{"gone.go"},
+		}
+		if !reflect.DeepEqual(changes, expected) {
+			t.Errorf("expected %+v, got %+v", expected, changes)
+		}
+	})
+
+	t.Run("changed since from a subdirectory", func(t *testing.T) {
+		changes, err := repo.ChangedSince(filepath.Join(dir, "web"), "HEAD~1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := []string{"app.js", "staged.js"}; !reflect.DeepEqual(changes.Modified, expected) {
+			t.Errorf("expected %v, got %v", expected, changes.Modified)
+		}
+		if len(changes.Deleted) != 0 {
+			t.Errorf("expected no deletions, got %v", changes.Deleted)
+		}
+	})
+
+	t.Run("changed between revisions", func(t *testing.T) {
+		changes, err := repo.ChangedBetween(dir, "HEAD~1", "HEAD")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := Changes{
+			Modified: []string{"added.go", "main.go", "web/reset.js"},
+			Deleted:  []string{"gone.go"},
+		}
+		if !reflect.DeepEqual(changes, expected) {
+			t.Errorf("expected %+v, got %+v", expected, changes)
+		}
+	})
+
+	t.Run("staged", func(t *testing.T) {
+		changes, err := repo.Staged(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := []string{"web/staged.js"}; !reflect.DeepEqual(changes.Modified, expected) {
+			t.Errorf("expected %v, got %v", expected, changes.Modified)
+		}
+	})
+
+	t.Run("unstaged", func(t *testing.T) {
+		changes, err := repo.Unstaged(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := []string{"web/app.js", "web/reset.js"}; !reflect.DeepEqual(changes.Modified, expected) {
+			t.Errorf("expected %v, got %v", expected, changes.Modified)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// TreeFS resolves rev to a commit and returns its tree as a read-only
+// fs.FS rooted at the top of the repository. Blobs are read from the object
+// database on demand, so the work tree is never touched.
+func (r *Repo) TreeFS(rev string) (fs.FS, error) {
+	commit, err := r.commit(rev)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("reading tree of %s: %w", rev, err)
+	}
+
+	return &treeFS{tree: tree, modTime: commit.Committer.When}, nil
+}
+
+func (r *Repo) commit(rev string) (*object.Commit, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("resolving revision %q: %w", rev, err)
+	}
+
+	commit, err := r.repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("reading commit %s: %w", hash, err)
+	}
+	return commit, nil
+}
+
+// Prefix returns dir relative to the work tree root in slash form, or "."
+// for the root itself, for use with fs.Sub on a TreeFS.
+func (r *Repo) Prefix(dir string) (string, error) {
+	prefix, err := r.prefix(dir)
+	if prefix == "" && err == nil {
+		prefix = "."
+	}
+	return prefix, err
+}
+
+type treeFS struct {
+	tree    *object.Tree
+	modTime time.Time
+}
+
+func (t *treeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		return &treeDir{fsys: t, tree: t.tree, info: t.dirInfo(".")}, nil
+	}
+
+	entry, err := t.tree.FindEntry(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if entry.Mode == filemode.Dir {
+		sub, err := t.tree.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &treeDir{fsys: t, tree: sub, info: t.dirInfo(path.Base(name))}, nil
+	}
+
+	file, err := t.tree.TreeEntryFile(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &treeFile{file: file, info: t.fileInfo(entry, file.Size)}, nil
+}
+
+func (t *treeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := t.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := f.(*treeDir)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return dir.ReadDir(-1)
+}
+
+func (t *treeFS) dirInfo(name string) *fileInfo {
+	return &fileInfo{name: name, mode: fs.ModeDir | 0o755, modTime: t.modTime}
+}
+
+func (t *treeFS) fileInfo(entry *object.TreeEntry, size int64) *fileInfo {
+	mode := fs.FileMode(0o644)
+	switch entry.Mode {
+	case filemode.Executable:
+		mode = 0o755
+	case filemode.Symlink:
+		mode = fs.ModeSymlink | 0o777
+	}
+	return &fileInfo{name: entry.Name, size: size, mode: mode, modTime: t.modTime}
+}
+
+type treeFile struct {
+	file   *object.File
+	info   *fileInfo
+	reader io.ReadCloser
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *treeFile) Read(p []byte) (int, error) {
+	if f.reader == nil {
+		r, err := f.file.Reader()
+		if err != nil {
+			return 0, err
+		}
+		f.reader = r
+	}
+	return f.reader.Read(p)
+}
+
+func (f *treeFile) Close() error {
+	if f.reader == nil {
+		return nil
+	}
+	return f.reader.Close()
+}
+
+type treeDir struct {
+	fsys    *treeFS
+	tree    *object.Tree
+	info    *fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *treeDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *treeDir) Close() error { return nil }
+
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		d.entries = d.list()
+	}
+
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
+
+// list returns the directory's entries sorted by name as fs.ReadDir
+// requires; git orders trees slightly differently. Submodules have no
+// content in this repository and are left out.
+func (d *treeDir) list() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(d.tree.Entries))
+	for i := range d.tree.Entries {
+		e := &d.tree.Entries[i]
+		switch e.Mode {
+		case filemode.Submodule:
+			continue
+		case filemode.Dir:
+			entries = append(entries, &dirEntry{info: d.fsys.dirInfo(e.Name)})
+		default:
+			entries = append(entries, &dirEntry{tree: d.tree, entry: e, fsys: d.fsys})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries
+}
+
+// dirEntry looks up a blob's size only when Info is called.
+type dirEntry struct {
+	fsys  *treeFS
+	tree  *object.Tree
+	entry *object.TreeEntry
+	info  *fileInfo
+}
+
+func (e *dirEntry) Name() string {
+	if e.info != nil {
+		return e.info.name
+	}
+	return e.entry.Name
+}
+
+func (e *dirEntry) IsDir() bool { return e.info != nil && e.info.IsDir() }
+
+func (e *dirEntry) Type() fs.FileMode {
+	if e.info != nil {
+		return e.info.Mode().Type()
+	}
+	return e.fsys.fileInfo(e.entry, 0).Mode().Type()
+}
+
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	if e.info == nil {
+		file, err := e.tree.TreeEntryFile(e.entry)
+		if err != nil {
+			return nil, err
+		}
+		e.info = e.fsys.fileInfo(e.entry, file.Size)
+	}
+	return e.info, nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Size() int64        { return i.size }
+func (i *fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *fileInfo) ModTime() time.Time { return i.modTime }
+func (i *fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *fileInfo) Sys() any           { return nil }
@@ -0,0 +1,111 @@
+package vcs
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func commitAll(t *testing.T, dir, message string) {
+	t.Helper()
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatalf("failed to open repository: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to open work tree: %v", err)
+	}
+	if err := wt.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		t.Fatalf("failed to stage files: %v", err)
+	}
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(1700000000, 0)}
+	if _, err := wt.Commit(message, &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+}
+
+func TestTreeFS(t *testing.T) {
+	dir := initRepo(t, map[string]string{
+		"main.go":        "package main\n",
+		"web/app.js":     "console.log(1)\n",
+		"web/lib/x.js":   "x\n",
+		"docs/readme.md": "# docs\n",
+	})
+	commitAll(t, dir, "first")
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "web", "app.js")); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatalf("failed to open repository: %v", err)
+	}
+	tree, err := repo.TreeFS("HEAD")
+	if err != nil {
+		t.Fatalf("failed to open tree: %v", err)
+	}
+
+	if err := fstest.TestFS(tree, "main.go", "web/app.js", "web/lib/x.js", "docs/readme.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := fs.ReadFile(tree, "main.go")
+	if err != nil {
+		t.Fatalf("failed to read main.go: %v", err)
+	}
+	if string(content) != "package main\n" {
+		t.Errorf("expected committed content, got %q", content)
+	}
+
+	info, err := fs.Stat(tree, "web/app.js")
+	if err != nil {
+		t.Fatalf("expected deleted work tree file to exist at HEAD: %v", err)
+	}
+	if info.Size() != int64(len("console.log(1)\n")) {
+		t.Errorf("unexpected size %d", info.Size())
+	}
+
+	if _, err := repo.TreeFS("no-such-branch"); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	dir := initRepo(t, map[string]string{"web/app.js": "app\n"})
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatalf("failed to open repository: %v", err)
+	}
+
+	tests := []struct {
+		dir      string
+		expected string
+	}{
+		{dir, "."},
+		{filepath.Join(dir, "web"), "web"},
+	}
+	for _, tt := range tests {
+		prefix, err := repo.Prefix(tt.dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if prefix != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, prefix)
+		}
+	}
+
+	if _, err := repo.Prefix(t.TempDir()); err == nil {
+		t.Error("expected error for a directory outside the work tree")
+	}
+}
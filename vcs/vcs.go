@@ -0,0 +1,105 @@
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+var ErrNotRepository = errors.New("not inside a git repository")
+
+// Repo is a git repository opened from a directory somewhere inside its
+// work tree.
+type Repo struct {
+	repo *git.Repository
+	Root string
+}
+
+func Open(dir string) (*Repo, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := git.PlainOpenWithOptions(abs, &git.PlainOpenOptions{
+		DetectDotGit:          true,
+		EnableDotGitCommonDir: true,
+	})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("%s: %w", dir, ErrNotRepository)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening repository: %w", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("opening work tree: %w", err)
+	}
+
+	return &Repo{repo: repo, Root: wt.Filesystem.Root()}, nil
+}
+
+// TrackedFiles returns the files recorded in the index that live under dir,
+// as slash-separated paths relative to dir. Submodules are left out.
+func (r *Repo) TrackedFiles(dir string) ([]string, error) {
+	prefix, err := r.prefix(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := r.repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("reading index: %w", err)
+	}
+
+	var files []string
+	seen := make(map[string]struct{})
+	for _, e := range idx.Entries {
+		if e.Mode == filemode.Submodule {
+			continue
+		}
+		rel, ok := trimPrefix(e.Name, prefix)
+		if !ok {
+			continue
+		}
+		if _, dup := seen[rel]; dup {
+			continue
+		}
+		seen[rel] = struct{}{}
+		files = append(files, rel)
+	}
+	return files, nil
+}
+
+// prefix returns dir relative to the work tree root in slash form, or ""
+// for the root itself.
+func (r *Repo) prefix(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(r.Root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the work tree %s", dir, r.Root)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func trimPrefix(name, prefix string) (string, bool) {
+	if prefix == "" {
+		return name, true
+	}
+	rest, ok := strings.CutPrefix(name, prefix+"/")
+	if !ok || rest == "" {
+		return "", false
+	}
+	return rest, true
+}
@@ -0,0 +1,90 @@
+package vcs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func initRepo(t *testing.T, files map[string]string, tracked ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("failed to init repository: %v", err)
+	}
+	for rel, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", rel, err)
+		}
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to open work tree: %v", err)
+	}
+	for _, rel := range tracked {
+		if _, err := wt.Add(rel); err != nil {
+			t.Fatalf("failed to add %s: %v", rel, err)
+		}
+	}
+	return dir
+}
+
+func TestTrackedFiles(t *testing.T) {
+	dir := initRepo(t, map[string]string{
+		".gitignore":    "*.gen.go\n",
+		"main.go":       "package main\n",
+		"api.gen.go":    "package main\n",
+		"web/app.js":    "app\n",
+		"web/dist/a.js": "dist\n",
+		"untracked.go":  "package main\n",
+	}, ".gitignore", "main.go", "api.gen.go", "web/app.js")
+
+	t.Run("from the root", func(t *testing.T) {
+		repo, err := Open(dir)
+		if err != nil {
+			t.Fatalf("failed to open repository: %v", err)
+		}
+		files, err := repo.TrackedFiles(dir)
+		if err != nil {
+			t.Fatalf("failed to list tracked files: %v", err)
+		}
+
+		expected := []string{".gitignore", "api.gen.go", "main.go", "web/app.js"}
+		if !reflect.DeepEqual(files, expected) {
+			t.Errorf("expected %v, got %v", expected, files)
+		}
+	})
+
+	t.Run("from a subdirectory", func(t *testing.T) {
+		sub := filepath.Join(dir, "web")
+		repo, err := Open(sub)
+		if err != nil {
+			t.Fatalf("failed to open repository: %v", err)
+		}
+		files, err := repo.TrackedFiles(sub)
+		if err != nil {
+			t.Fatalf("failed to list tracked files: %v", err)
+		}
+
+		if expected := []string{"app.js"}; !reflect.DeepEqual(files, expected) {
+			t.Errorf("expected %v, got %v", expected, files)
+		}
+	})
+}
+
+func TestOpenNotRepository(t *testing.T) {
+	if _, err := Open(t.TempDir()); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("expected ErrNotRepository, got %v", err)
+	}
+}